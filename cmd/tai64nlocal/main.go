@@ -0,0 +1,31 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+// Command tai64nlocal converts TAI64N timestamps to local time. It reads lines
+// from stdin, replaces a leading TAI64N label such as
+// "@4000000037c219bf2ef02e94" with a human readable local time, and writes the
+// result to stdout. Lines without a label are written unchanged.
+//
+// It is a replacement for the tai64nlocal command in daemontools. See
+// http://cr.yp.to/daemontools/tai64nlocal.html for more information.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/paulhammond/tai64"
+)
+
+func main() {
+	if err := convert(os.Stdin, os.Stdout, time.Local); err != nil {
+		fmt.Fprintln(os.Stderr, "tai64nlocal:", err)
+		os.Exit(1)
+	}
+}
+
+func convert(r io.Reader, w io.Writer, loc *time.Location) error {
+	return tai64.ConvertLog(r, w, tai64.LocalLayout, loc)
+}
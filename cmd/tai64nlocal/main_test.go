@@ -0,0 +1,106 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestConvert(t *testing.T) {
+	in := "@4000000037c219bf2ef02e94 first line\n" +
+		"no timestamp\n" +
+		"@4000000052c65e550cd675fc\tsecond  line \n" +
+		"\n" +
+		"@4000000037c219bf2ef02e9 too short\n" +
+		"@G000000037c219bf2ef02e94 not hex\n" +
+		"@4000000043b9410600000000 no newline"
+	expected := "1999-08-24 04:03:43.787492500 first line\n" +
+		"no timestamp\n" +
+		"2014-01-03 06:52:34.215381500\tsecond  line \n" +
+		"\n" +
+		"@4000000037c219bf2ef02e9 too short\n" +
+		"@G000000037c219bf2ef02e94 not hex\n" +
+		"2006-01-02 15:04:05.000000000 no newline"
+
+	var out bytes.Buffer
+	if err := convert(strings.NewReader(in), &out, time.UTC); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("got %q, expected %q", out.String(), expected)
+	}
+
+	out.Reset()
+	loc := time.FixedZone("PDT", -7*60*60)
+	if err := convert(strings.NewReader(in[:37]), &out, loc); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if e := "1999-08-23 21:03:43.787492500 first line\n"; out.String() != e {
+		t.Errorf("got %q, expected %q", out.String(), e)
+	}
+}
+
+// TestGolden converts each testdata/*.input file in UTC and compares the result
+// with the matching .golden file. Run with -update to rewrite the golden files
+// after an intended change in output.
+//
+// The golden files record the output of this command, not of the daemontools
+// tai64nlocal. The daemontools version subtracts 10 seconds from the TAI label
+// and passes the result to localtime, so it only prints UTC when run with
+// TZ=right/UTC; under TZ=UTC every line is off by the leap seconds since 1972.
+// Compared with daemontools under TZ=right/UTC these files differ on purpose
+// in the following lines:
+//
+//   - multilog.input "too short": daemontools reads every hex digit after the
+//     "@", so it converts a 23 digit label to a meaningless date. This command
+//     only converts complete 24 digit labels and leaves the line unchanged.
+//   - multilog.input "followed by a digit": daemontools reads all 25 digits and
+//     treats the last 8 as nanoseconds. This command converts the first 24
+//     digits and writes the extra "4" as part of the rest of the line.
+//   - leap.input "the leap second" and "half way through the leap second":
+//     daemontools prints 2016-12-31 23:59:60.000000000 and 23:59:60.500000000.
+//     time.Time cannot represent second 60, so this command prints the
+//     following second, 2017-01-01 00:00:00.000000000 and 00:00:00.500000000,
+//     and the times in leap.golden briefly run backwards.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no input files found")
+	}
+	for _, input := range inputs {
+		in, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := convert(bytes.NewReader(in), &out, time.UTC); err != nil {
+			t.Fatalf("%v: expected nil error, got %v", input, err)
+		}
+		golden := strings.TrimSuffix(input, ".input") + ".golden"
+		if *update {
+			if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), expected) {
+			t.Errorf("%v: got\n%s\nexpected\n%s", input, out.Bytes(), expected)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"sort"
+	"time"
+)
+
+// Converter converts between TAI64 labels and times using its own table of
+// leap seconds, instead of the package table that LoadLeapSeconds replaces.
+// A Converter cannot be changed once created, so can be shared freely between
+// goroutines. The zero Converter has no leap seconds, so TAI is always 10
+// seconds ahead of UTC.
+type Converter struct {
+	table []int64
+	// expires is when table expires in seconds since the unix epoch, or zero
+	// if it is not known
+	expires int64
+}
+
+// NewConverter returns a Converter using the leap seconds in leaps. As with
+// LeapSeconds, each is the UTC time immediately after the leap second was
+// inserted, for example 2017-01-01T00:00:00Z for the last second of 2016. They
+// can be in any order, and any fraction of a second is ignored. Leap seconds
+// were first inserted after 1972-01-01T00:00:00Z, when TAI was set to 10
+// seconds ahead of UTC, so any at or before that time are ignored.
+func NewConverter(leaps []time.Time) *Converter {
+	secs := make([]int64, len(leaps))
+	for i, t := range leaps {
+		secs[i] = t.Unix()
+	}
+	sort.Slice(secs, func(i, j int) bool { return secs[i] < secs[j] })
+	// the initial 10 second offset, then each leap second in TAI
+	table := []int64{63072009}
+	for i, s := range secs {
+		// the table must stay sorted, so nothing can come before the
+		// initial offset
+		if s <= 63072000 || i > 0 && s == secs[i-1] {
+			continue
+		}
+		// each leap second is one more second ahead of UTC than the last
+		table = append(table, s+int64(len(table)+9))
+	}
+	return &Converter{table: table}
+}
+
+// TAItoUTC is like the package function TAItoUTC, but uses the leap seconds of
+// c.
+func (c *Converter) TAItoUTC(taiSecs int64) int64 {
+	table := c.table
+	// the number of entries before taiSecs
+	n := sort.Search(len(table), func(i int) bool { return table[i] >= taiSecs })
+	return taiSecs - offset(n)
+}
+
+// UTCtoTAI is like the package function UTCtoTAI, but uses the leap seconds of
+// c.
+func (c *Converter) UTCtoTAI(utcSecs int64) int64 {
+	table := c.table
+	// the number of entries before utcSecs; entry i is i+9 seconds ahead of
+	// the unix time it takes effect
+	n := sort.Search(len(table), func(i int) bool { return table[i]-int64(i+9) > utcSecs })
+	return utcSecs + offset(n)
+}
+
+// LeapSeconds returns the leap seconds used by c, in ascending order, in the
+// same form as the package function LeapSeconds.
+func (c *Converter) LeapSeconds() []time.Time {
+	table := c.table
+	if len(table) == 0 {
+		return nil
+	}
+	// the oldest entry is the initial 10 second offset, not a leap second
+	leaps := make([]time.Time, len(table)-1)
+	for i := range leaps {
+		// i previous leap seconds, plus the initial offset
+		leaps[i] = time.Unix(table[i+1]-int64(i+10), 0).UTC()
+	}
+	return leaps
+}
+
+// IsLeapSecond reports whether l falls within one of the leap seconds of c.
+func (c *Converter) IsLeapSecond(l Tai64n) bool {
+	return c.isLeapSecond(int64(l.Label - (1 << 62)))
+}
+
+// isLeapSecond reports whether secs seconds since the beginning of 1970 TAI
+// falls within one of the leap seconds of c.
+func (c *Converter) isLeapSecond(secs int64) bool {
+	table := c.table
+	i := sort.Search(len(table), func(i int) bool { return table[i] >= secs })
+	// the first entry is the initial 10 second offset, not a leap second
+	return i > 0 && i < len(table) && table[i] == secs
+}
+
+// EpochTime is like the package function EpochTime, but uses the leap seconds
+// of c.
+func (c *Converter) EpochTime(secs, nsecs int64) time.Time {
+	return time.Unix(c.TAItoUTC(secs), nsecs)
+}
+
+// DecodeTai64 is like the package function DecodeTai64, but uses the leap
+// seconds of c.
+func (c *Converter) DecodeTai64(b []byte) (time.Time, error) {
+	secs, err := DecodeLabel(b, 1<<62)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.EpochTime(secs, 0), nil
+}
+
+// DecodeTai64n is like the package function DecodeTai64n, but uses the leap
+// seconds of c.
+func (c *Converter) DecodeTai64n(b []byte) (time.Time, error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.EpochTime(int64(l.Label-(1<<62)), int64(l.Nanoseconds)), nil
+}
+
+// ParseTai64n is like the package function ParseTai64n, but uses the leap
+// seconds of c.
+func (c *Converter) ParseTai64n(s string) (time.Time, error) {
+	l, err := ParseTai64nLabel(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.EpochTime(int64(l.Label-(1<<62)), int64(l.Nanoseconds)), nil
+}
+
+// Tai64n is like NewTai64n, but uses the leap seconds of c.
+func (c *Converter) Tai64n(t time.Time) Tai64n {
+	return Tai64n{uint64(c.UTCtoTAI(t.Unix())) + 1<<62, uint32(t.Nanosecond())}
+}
+
+// EncodeTai64 is like the package function EncodeTai64, but uses the leap
+// seconds of c.
+func (c *Converter) EncodeTai64(t time.Time) []byte {
+	return Tai64{uint64(c.UTCtoTAI(t.Unix())) + 1<<62}.Bytes()
+}
+
+// EncodeTai64n is like the package function EncodeTai64n, but uses the leap
+// seconds of c.
+func (c *Converter) EncodeTai64n(t time.Time) []byte {
+	return c.Tai64n(t).Bytes()
+}
+
+// FormatTai64n is like the package function FormatTai64n, but uses the leap
+// seconds of c.
+func (c *Converter) FormatTai64n(t time.Time) string {
+	return c.Tai64n(t).String()
+}
@@ -0,0 +1,171 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewConverter(t *testing.T) {
+	c := NewConverter(LeapSeconds())
+	if !reflect.DeepEqual(c.table, leapSeconds) {
+		t.Errorf("got %v, expected %v", c.table, leapSeconds)
+	}
+	if !reflect.DeepEqual(c.LeapSeconds(), LeapSeconds()) {
+		t.Errorf("got %v, expected %v", c.LeapSeconds(), LeapSeconds())
+	}
+
+	// order, duplicates and fractions of a second are ignored
+	leaps := LeapSeconds()
+	in := append([]time.Time{leaps[3].Add(500 * time.Millisecond)}, leaps...)
+	for i, j := 0, len(in)-1; i < j; i, j = i+1, j-1 {
+		in[i], in[j] = in[j], in[i]
+	}
+	if out := NewConverter(in).table; !reflect.DeepEqual(out, leapSeconds) {
+		t.Errorf("got %v, expected %v", out, leapSeconds)
+	}
+
+	// leap seconds at or before the start of 1972 are ignored, and the table
+	// stays sorted
+	early := []time.Time{
+		time.Date(1972, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1971, 7, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	c = NewConverter(append(early, leaps...))
+	if !reflect.DeepEqual(c.table, leapSeconds) {
+		t.Errorf("got %v, expected %v", c.table, leapSeconds)
+	}
+	if !sort.SliceIsSorted(c.table, func(i, j int) bool { return c.table[i] < c.table[j] }) {
+		t.Errorf("expected %v to be sorted", c.table)
+	}
+	if out := c.UTCtoTAI(1483228800); out != 1483228837 {
+		t.Errorf("got %v, expected %v", out, 1483228837)
+	}
+	if out := c.TAItoUTC(1483228837); out != 1483228800 {
+		t.Errorf("got %v, expected %v", out, 1483228800)
+	}
+	if c := NewConverter(early); len(c.LeapSeconds()) != 0 || c.UTCtoTAI(0) != 10 {
+		t.Errorf("got %v, expected no leap seconds", c.LeapSeconds())
+	}
+
+	// no leap seconds at all
+	c = NewConverter(nil)
+	if out := c.UTCtoTAI(1483228800); out != 1483228810 {
+		t.Errorf("got %v, expected %v", out, 1483228810)
+	}
+	if out := c.LeapSeconds(); len(out) != 0 {
+		t.Errorf("got %v, expected no leap seconds", out)
+	}
+}
+
+func TestZeroConverter(t *testing.T) {
+	var c Converter
+	if out := c.LeapSeconds(); len(out) != 0 {
+		t.Errorf("got %v, expected no leap seconds", out)
+	}
+	if out := c.UTCtoTAI(1483228800); out != 1483228810 {
+		t.Errorf("got %v, expected %v", out, 1483228810)
+	}
+	if out := c.TAItoUTC(1483228810); out != 1483228800 {
+		t.Errorf("got %v, expected %v", out, 1483228800)
+	}
+	if c.IsLeapSecond(Tai64n{1<<62 + 1483228836, 0}) {
+		t.Errorf("expected no leap seconds")
+	}
+}
+
+func TestConverter(t *testing.T) {
+	c := NewConverter(LeapSeconds())
+	for _, test := range tai64nTests {
+		result, err := c.DecodeTai64n(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+		if out := c.EncodeTai64n(result); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+		result, err = c.ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	for _, test := range tai64Tests {
+		result, err := c.DecodeTai64(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+		if out := c.EncodeTai64(result); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+	}
+
+	if _, err := c.DecodeTai64n([]byte{0x40}); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+	if _, err := c.DecodeTai64([]byte{0x40}); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+	if _, err := c.ParseTai64n("@4000000037c219bf"); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestConverterExtraLeapSecond(t *testing.T) {
+	// a hypothetical leap second at the end of June 2025
+	leap := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	c := NewConverter(append(LeapSeconds(), leap))
+
+	tests := []struct {
+		utc string
+		tai int64
+		hex string
+	}{
+		{"2020-01-01T00:00:00Z", 1577836800 + 37, "@400000005e0be12500000000"},
+		{"2025-06-30T23:59:59Z", 1751327999 + 37, "@400000006863252400000000"},
+		{"2025-07-01T00:00:00Z", 1751328000 + 38, "@400000006863252600000000"},
+		{"2030-01-01T00:00:00Z", 1893456000 + 38, "@4000000070dbd8a600000000"},
+	}
+	for _, test := range tests {
+		utc, _ := time.Parse(time.RFC3339, test.utc)
+		if out := c.UTCtoTAI(utc.Unix()); out != test.tai {
+			t.Errorf("UTCtoTAI(%v): got %d, expected %d", test.utc, out, test.tai)
+		}
+		if out := c.EpochTime(test.tai, 0); !out.Equal(utc) {
+			t.Errorf("EpochTime(%d): got %v, expected %v", test.tai, out, test.utc)
+		}
+		if out := c.FormatTai64n(utc); out != test.hex {
+			t.Errorf("FormatTai64n(%v): got %v, expected %v", test.utc, out, test.hex)
+		}
+	}
+
+	in, _ := ParseTai64nLabel("@400000006863252500000000")
+	if !c.IsLeapSecond(in) {
+		t.Errorf("expected 2025-06-30T23:59:60Z to be a leap second")
+	}
+	if in.IsLeapSecond() {
+		t.Errorf("expected the package table to be unchanged")
+	}
+	if out := c.LeapSeconds(); len(out) != len(LeapSeconds())+1 || !out[len(out)-1].Equal(leap) {
+		t.Errorf("got %v, expected %v at the end", out, leap)
+	}
+	// the package level functions are unaffected
+	if out := UTCtoTAI(1893456000); out != 1893456000+37 {
+		t.Errorf("got %d, expected %d", out, 1893456000+37)
+	}
+}
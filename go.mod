@@ -1,3 +1,3 @@
 module github.com/paulhammond/tai64
 
-go 1.11
+go 1.18
@@ -0,0 +1,29 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import "time"
+
+// GPSOffset is how far TAI is ahead of GPS time. GPS time was set to UTC at
+// its epoch, 1980-01-06T00:00:00Z, when TAI was 19 seconds ahead of UTC, and
+// like TAI it does not have leap seconds, so the difference never changes.
+const GPSOffset = 19 * time.Second
+
+// ToGPS returns t, a time in UTC, in the GPS time scale. As with
+// DecodeTai64nTAI the result does not represent the correct instant; formatting
+// it in UTC shows the GPS date and time, which is ahead of UTC by the number of
+// leap seconds since 1980. For example 2017-01-01T00:00:00Z is
+// 2017-01-01T00:00:18 in GPS time.
+func ToGPS(t time.Time) time.Time {
+	secs := UTCtoTAI(t.Unix()) - int64(GPSOffset/time.Second)
+	return time.Unix(secs, int64(t.Nanosecond())).UTC()
+}
+
+// FromGPS returns the UTC time for gps, a time in the GPS time scale as
+// returned by ToGPS. The location of gps is ignored. A time within a leap
+// second becomes the following second, as with DecodeTai64n.
+func FromGPS(gps time.Time) time.Time {
+	secs := gps.Unix() + int64(GPSOffset/time.Second)
+	return time.Unix(TAItoUTC(secs), int64(gps.Nanosecond()))
+}
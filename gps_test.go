@@ -0,0 +1,59 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGPS(t *testing.T) {
+	tests := []struct {
+		utc string
+		gps string
+	}{
+		// the GPS epoch
+		{"1980-01-06T00:00:00Z", "1980-01-06T00:00:00Z"},
+		// before the leap second at the end of 1979 GPS time would have been
+		// behind UTC
+		{"1980-01-01T00:00:00Z", "1980-01-01T00:00:00Z"},
+		{"1979-12-31T23:59:59Z", "1979-12-31T23:59:58Z"},
+		// GPS week 1024, when older receivers first rolled over, was 13 seconds
+		// ahead of UTC
+		{"1999-08-21T23:59:47Z", "1999-08-22T00:00:00Z"},
+		// the last second of 2016 and the second after the leap second
+		{"2016-12-31T23:59:59.5Z", "2017-01-01T00:00:16.5Z"},
+		{"2017-01-01T00:00:00Z", "2017-01-01T00:00:18Z"},
+	}
+	for _, test := range tests {
+		utc, err := time.Parse(time.RFC3339Nano, test.utc)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		gps, err := time.Parse(time.RFC3339Nano, test.gps)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := ToGPS(utc); !out.Equal(gps) {
+			t.Errorf("%v: got %v, expected %v", test.utc, out, gps)
+		}
+		if out := FromGPS(gps); !out.Equal(utc) {
+			t.Errorf("%v: got %v, expected %v", test.gps, out, utc)
+		}
+	}
+
+	// the leap second at the end of 2016 is a GPS second of its own, which
+	// becomes the following UTC second
+	leap := time.Date(2017, 1, 1, 0, 0, 17, 0, time.UTC)
+	if out, expected := FromGPS(leap), time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC); !out.Equal(expected) {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+
+	// GPS time is always GPSOffset behind TAI
+	in := time.Date(2014, 1, 3, 6, 52, 34, 215381500, time.UTC)
+	tai, _ := DecodeTai64nTAI(EncodeTai64n(in))
+	if d := tai.Sub(ToGPS(in)); d != GPSOffset {
+		t.Errorf("got %v, expected %v", d, GPSOffset)
+	}
+}
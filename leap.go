@@ -0,0 +1,330 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// nowFunc returns the current time. It can be replaced in tests.
+var nowFunc = time.Now
+
+// current holds the *Converter used by the package level functions. It is
+// replaced, never modified, when leap seconds are loaded, so that conversions
+// running in other goroutines see either the old table or the new one.
+var current atomic.Value
+
+func init() {
+	leapSeconds = sortLeapSeconds(leapSeconds)
+	setLeapSeconds(leapSeconds, leapSecondsExpiry)
+}
+
+// setLeapSeconds replaces the package table of leap seconds with table, which
+// must be sorted and must not be modified afterwards. The table expires at
+// expires seconds since the unix epoch, or never if it is zero.
+func setLeapSeconds(table []int64, expires int64) {
+	current.Store(&Converter{table, expires})
+}
+
+// sortLeapSeconds returns a copy of table sorted into ascending order, as
+// expected by TAItoUTC and UTCtoTAI, with any duplicates removed.
+func sortLeapSeconds(table []int64) []int64 {
+	sorted := make([]int64, len(table))
+	copy(sorted, table)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := 0
+	for i, l := range sorted {
+		if i == 0 || l != sorted[n-1] {
+			sorted[n] = l
+			n++
+		}
+	}
+	return sorted[:n]
+}
+
+// LoadLeapSeconds replaces the table of leap seconds with one read from r,
+// which must be in the format of the IANA leap-seconds.list file. See
+// https://www.ietf.org/timezones/data/leap-seconds.list for an example.
+//
+// An Error is returned, and the table left unchanged, if the file is malformed,
+// has no expiry date or has expired.
+func LoadLeapSeconds(r io.Reader) error {
+	var table []int64
+	var expires, prev int64
+	offset := int64(9)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		// "#@" lines contain the expiry date as an NTP timestamp
+		if strings.HasPrefix(line, "#@") {
+			e, err := strconv.ParseInt(strings.TrimSpace(line[2:]), 10, 64)
+			if err != nil {
+				return leapError(n)
+			}
+			expires = e - ntpEpoch
+			continue
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 2 {
+			return leapError(n)
+		}
+		ntp, err := strconv.ParseInt(f[0], 10, 64)
+		if err != nil {
+			return leapError(n)
+		}
+		o, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return leapError(n)
+		}
+		// each line adds one second to the initial offset of 10
+		if o != offset+1 {
+			return leapError(n)
+		}
+		if ntp <= prev {
+			return leapError(n)
+		}
+		table = append(table, ntp-ntpEpoch+offset)
+		offset, prev = o, ntp
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if len(table) == 0 {
+		return Error{"tai64: no leap seconds found", ""}
+	}
+	if expires == 0 {
+		return Error{"tai64: leap seconds expiry date not found", ""}
+	}
+	if nowFunc().Unix() >= expires {
+		return Error{"tai64: leap seconds file expired on " + time.Unix(expires, 0).UTC().Format("2006-01-02"), ""}
+	}
+	setLeapSeconds(table, expires)
+	return nil
+}
+
+// LoadLeapSecondsFromZoneinfo replaces the table of leap seconds with the leap
+// second records in the TZif file at path. Only files from the "right"
+// zoneinfo directories, such as /usr/share/zoneinfo/right/UTC, contain leap
+// seconds. Files before version 4 of the format have no expiry date, so
+// LeapSecondsExpired always reports false after loading one.
+//
+// An Error is returned, and the table left unchanged, if the file is not a
+// valid TZif file or has no leap seconds.
+func LoadLeapSecondsFromZoneinfo(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	table, expires, err := tzifLeapSeconds(b)
+	if err != nil {
+		return err
+	}
+	setLeapSeconds(table, expires)
+	return nil
+}
+
+// tzifLeapSeconds returns a leap second table from the leap second records in
+// the TZif data b, and the unix time it expires or zero if it has no expiry
+// record. See RFC 8536 for details of the format.
+func tzifLeapSeconds(b []byte) ([]int64, int64, error) {
+	invalid := Error{"tai64: invalid zoneinfo file", ""}
+	c, ok := tzifHeader(b)
+	if !ok {
+		return nil, 0, invalid
+	}
+	// version 1 data uses 4 byte times. Later versions follow it with a second
+	// header and data block using 8 byte times.
+	tsize := 4
+	if b[4] >= '2' {
+		if uint64(len(b)-44) < c.size(4) {
+			return nil, 0, invalid
+		}
+		b = b[44+int(c.size(4)):]
+		if c, ok = tzifHeader(b); !ok {
+			return nil, 0, invalid
+		}
+		tsize = 8
+	}
+	data := b[44:]
+	if uint64(len(data)) < c.size(tsize) {
+		return nil, 0, invalid
+	}
+	// leap second records follow the transition times, transition types, local
+	// time types and time zone designations
+	data = data[c.timecnt*(tsize+1)+c.typecnt*6+c.charcnt:]
+
+	// the first entry is the initial 10 second offset
+	table := []int64{63072009}
+	var correction, expires int64
+	for i := 0; i < c.leapcnt; i++ {
+		r := data[i*(tsize+4):]
+		var occurrence int64
+		if tsize == 4 {
+			occurrence = int64(int32(binary.BigEndian.Uint32(r)))
+		} else {
+			occurrence = int64(binary.BigEndian.Uint64(r))
+		}
+		corr := int64(int32(binary.BigEndian.Uint32(r[tsize:])))
+		// version 4 files can end with a record that does not change the
+		// correction, which marks when the table expires
+		if i > 0 && i == c.leapcnt-1 && corr == correction {
+			expires = occurrence - correction
+			break
+		}
+		// the occurrence already includes previous leap seconds, so only
+		// the initial offset needs to be added
+		l := occurrence + 10
+		if corr != correction+1 || l <= table[len(table)-1] {
+			return nil, 0, invalid
+		}
+		table = append(table, l)
+		correction = corr
+	}
+	if len(table) == 1 {
+		return nil, 0, Error{"tai64: no leap seconds found", ""}
+	}
+	return table, expires, nil
+}
+
+// tzifCounts holds the number of each kind of record in a TZif data block.
+type tzifCounts struct {
+	isutcnt, isstdcnt, leapcnt, timecnt, typecnt, charcnt int
+}
+
+// size returns the length of a data block using tsize byte times. It is a
+// uint64 so that large counts cannot overflow on 32 bit platforms.
+func (c tzifCounts) size(tsize int) uint64 {
+	n := func(count, size int) uint64 { return uint64(count) * uint64(size) }
+	return n(c.timecnt, tsize+1) + n(c.typecnt, 6) + n(c.charcnt, 1) + n(c.leapcnt, tsize+4) + n(c.isstdcnt, 1) + n(c.isutcnt, 1)
+}
+
+// tzifHeader parses the TZif header at the start of b, which is the magic
+// "TZif", a version, 15 unused bytes and six counts. It reports false if any
+// count is larger than the length of b, as the data cannot then be complete.
+func tzifHeader(b []byte) (tzifCounts, bool) {
+	if len(b) < 44 || string(b[:4]) != "TZif" {
+		return tzifCounts{}, false
+	}
+	var counts [6]int
+	for i := range counts {
+		n := uint64(binary.BigEndian.Uint32(b[20+4*i:]))
+		if n > uint64(len(b)) {
+			return tzifCounts{}, false
+		}
+		counts[i] = int(n)
+	}
+	return tzifCounts{counts[0], counts[1], counts[2], counts[3], counts[4], counts[5]}, true
+}
+
+func leapError(line int) error {
+	return Error{fmt.Sprintf("tai64: malformed leap seconds on line %d", line), ""}
+}
+
+// defaultConverter returns the Converter used by the package level functions.
+func defaultConverter() *Converter {
+	return current.Load().(*Converter)
+}
+
+// TAItoUTC converts taiSecs seconds since the beginning of 1970 TAI into
+// seconds since the unix epoch, which do not count leap seconds. A leap second
+// returns the same result as the second after it.
+func TAItoUTC(taiSecs int64) int64 {
+	return defaultConverter().TAItoUTC(taiSecs)
+}
+
+// UTCtoTAI converts utcSecs seconds since the unix epoch into seconds since
+// the beginning of 1970 TAI. It is the inverse of TAItoUTC; a second
+// immediately following a leap second returns the later of the two TAI
+// seconds.
+func UTCtoTAI(utcSecs int64) int64 {
+	return defaultConverter().UTCtoTAI(utcSecs)
+}
+
+// offset returns the difference between TAI and UTC once n entries of the leap
+// second table have passed.
+func offset(n int) int64 {
+	// the first entry is the initial 10 second offset, not a leap second
+	if n == 0 {
+		return 10
+	}
+	return int64(n) + 9
+}
+
+// Between returns the elapsed time from a to b, including any leap seconds
+// inserted between them. This differs from b.Sub(a), which ignores leap
+// seconds.
+func Between(a, b time.Time) time.Duration {
+	secs := UTCtoTAI(b.Unix()) - UTCtoTAI(a.Unix())
+	return time.Duration(secs)*time.Second + time.Duration(b.Nanosecond()-a.Nanosecond())
+}
+
+// LeapSecondsExpiry returns the time after which the table of leap seconds
+// should no longer be trusted, as a leap second may have been announced since.
+// This is the expiry date of the file given to LoadLeapSeconds, or of the file
+// the built in table was last checked against. It is the zero time if the
+// table has no expiry date.
+func LeapSecondsExpiry() time.Time {
+	c := defaultConverter()
+	if c.expires == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.expires, 0).UTC()
+}
+
+// LeapSecondsExpired reports whether the table of leap seconds has passed its
+// expiry date, in which case applications may want to warn that it needs
+// updating.
+func LeapSecondsExpired() bool {
+	c := defaultConverter()
+	return c.expires != 0 && nowFunc().Unix() >= c.expires
+}
+
+// TaiSeconds returns d as a whole number of TAI seconds, truncated towards
+// zero. Every TAI second is the same length, so a duration in TAI seconds is
+// an exact elapsed time, unlike the difference between two UTC times that
+// spans a leap second. The result of Between can be converted this way.
+func TaiSeconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+// DurationFromTaiSeconds returns secs TAI seconds as a time.Duration. It is the
+// inverse of TaiSeconds.
+func DurationFromTaiSeconds(secs int64) time.Duration {
+	return time.Duration(secs) * time.Second
+}
+
+// OffsetAt returns how far TAI is ahead of UTC at t. This is 10 seconds before
+// the first leap second in 1972, plus one second for each leap second since.
+func OffsetAt(t time.Time) time.Duration {
+	secs := t.Unix()
+	return time.Duration(UTCtoTAI(secs)-secs) * time.Second
+}
+
+// LeapSeconds returns the leap seconds known to this package, in ascending
+// order. Each is returned as the UTC time immediately after the leap second
+// was inserted, for example 2017-01-01T00:00:00Z for the last second of 2016.
+func LeapSeconds() []time.Time {
+	return defaultConverter().LeapSeconds()
+}
+
+// isLeapSecond reports whether secs seconds since the beginning of 1970 TAI
+// falls within an inserted leap second.
+func isLeapSecond(secs int64) bool {
+	return defaultConverter().isLeapSecond(secs)
+}
@@ -0,0 +1,544 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const leapSecondsList = `#
+#	Updated through IERS Bulletin C 65
+#	File expires on:  28 December 2023
+#
+#@	3912710400
+#
+2272060800	10	# 1 Jan 1972
+2287785600	11	# 1 Jul 1972
+2303683200	12	# 1 Jan 1973
+#h	16edd0f0 3666784f 37db6bdd e74ced87 59af48f1
+`
+
+func setNow(t time.Time) func() {
+	nowFunc = func() time.Time { return t }
+	return func() { nowFunc = time.Now }
+}
+
+func restoreLeapSeconds() func() {
+	saved := defaultConverter()
+	return func() { current.Store(saved) }
+}
+
+func TestLoadLeapSeconds(t *testing.T) {
+	defer restoreLeapSeconds()()
+	defer setNow(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))()
+
+	if err := LoadLeapSeconds(strings.NewReader(leapSecondsList)); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	expected := []int64{63072009, 78796810, 94694411}
+	if !reflect.DeepEqual(defaultConverter().table, expected) {
+		t.Errorf("got %v, expected %v", defaultConverter().table, expected)
+	}
+	// the table is now missing the leap second at the end of 1973
+	if out, expected := EpochTime(1<<30, 0), time.Unix(1<<30-12, 0); !out.Equal(expected) {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+
+	bad := []struct {
+		in  string
+		err string
+	}{
+		{strings.Replace(leapSecondsList, "3912710400", "soon", 1), "tai64: malformed leap seconds on line 5"},
+		{strings.Replace(leapSecondsList, "\t11\t", "\t11\t12\t", 1), "tai64: malformed leap seconds on line 8"},
+		{strings.Replace(leapSecondsList, "\t11\t", "\televen\t", 1), "tai64: malformed leap seconds on line 8"},
+		{strings.Replace(leapSecondsList, "\t11\t", "\t12\t", 1), "tai64: malformed leap seconds on line 8"},
+		{strings.Replace(leapSecondsList, "2303683200", "2287785600", 1), "tai64: malformed leap seconds on line 9"},
+		{strings.Replace(leapSecondsList, "#@", "#", 1), "tai64: leap seconds expiry date not found"},
+		{"#@\t3912710400\n", "tai64: no leap seconds found"},
+		{strings.Replace(leapSecondsList, "3912710400", "3849638400", 1), "tai64: leap seconds file expired on 2021-12-28"},
+	}
+	for _, test := range bad {
+		err := LoadLeapSeconds(strings.NewReader(test.in))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("expected %v, got %v", test.err, err)
+		}
+		if !reflect.DeepEqual(defaultConverter().table, expected) {
+			t.Errorf("expected leap seconds to be unchanged, got %v", defaultConverter().table)
+		}
+	}
+}
+
+func TestLoadLeapSecondsConcurrent(t *testing.T) {
+	defer restoreLeapSeconds()()
+	defer setNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))()
+
+	// the time decoded with each table
+	in := tai64nTests[0]
+	if err := LoadLeapSeconds(strings.NewReader(leapSecondsList)); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	loaded, _ := DecodeTai64n(in.bytes)
+	setLeapSeconds(leapSeconds, leapSecondsExpiry)
+	builtin, _ := DecodeTai64n(in.bytes)
+
+	done := make(chan struct{})
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					errs <- nil
+					return
+				default:
+				}
+				result, err := DecodeTai64n(in.bytes)
+				if err == nil && !result.Equal(builtin) && !result.Equal(loaded) {
+					err = fmt.Errorf("got %v, expected %v or %v", result, builtin, loaded)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				EncodeTai64n(result)
+				LeapSeconds()
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if err := LoadLeapSeconds(strings.NewReader(leapSecondsList)); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		setLeapSeconds(leapSeconds, leapSecondsExpiry)
+	}
+	close(done)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLeapSecondsExpiry(t *testing.T) {
+	defer restoreLeapSeconds()()
+
+	builtin := time.Unix(leapSecondsExpiry, 0)
+	if out := LeapSecondsExpiry(); !out.Equal(builtin) {
+		t.Errorf("got %v, expected %v", out, builtin)
+	}
+	tests := []struct {
+		now     time.Time
+		expired bool
+	}{
+		{builtin.Add(-time.Second), false},
+		{builtin, true},
+		{builtin.AddDate(3, 0, 0), true},
+	}
+	for _, test := range tests {
+		restore := setNow(test.now)
+		if out := LeapSecondsExpired(); out != test.expired {
+			t.Errorf("%v: got %v, expected %v", test.now, out, test.expired)
+		}
+		restore()
+	}
+
+	// the "#@" line of the file is 28 December 2023
+	defer setNow(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))()
+	if err := LoadLeapSeconds(strings.NewReader(leapSecondsList)); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	expected := time.Date(2023, 12, 28, 0, 0, 0, 0, time.UTC)
+	if out := LeapSecondsExpiry(); !out.Equal(expected) {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+	if LeapSecondsExpired() {
+		t.Errorf("expected leap seconds not to have expired")
+	}
+	defer setNow(expected)()
+	if !LeapSecondsExpired() {
+		t.Errorf("expected leap seconds to have expired")
+	}
+
+	// a custom converter has no expiry date
+	if c := NewConverter(LeapSeconds()); c.expires != 0 {
+		t.Errorf("got %v, expected no expiry", c.expires)
+	}
+}
+
+// TestBuiltinLeapSecondsExpiry compares the built in table with the current
+// date, so it only runs when TAI64_CHECK_LEAP_SECONDS is set. If it fails,
+// update leapSeconds and leapSecondsExpiry from the latest
+// https://data.iana.org/time-zones/data/leap-seconds.list
+func TestBuiltinLeapSecondsExpiry(t *testing.T) {
+	if os.Getenv("TAI64_CHECK_LEAP_SECONDS") == "" {
+		t.Skip("set TAI64_CHECK_LEAP_SECONDS to check the built in leap seconds")
+	}
+	if expiry := time.Unix(leapSecondsExpiry, 0).UTC(); !time.Now().Before(expiry) {
+		t.Errorf("the built in leap seconds expired on %v", expiry.Format("2006-01-02"))
+	}
+}
+
+func TestLeapSeconds(t *testing.T) {
+	leaps := LeapSeconds()
+	if len(leaps) != 27 {
+		t.Errorf("got %d leap seconds, expected 27", len(leaps))
+	}
+	expected := map[int]string{
+		0:  "1972-07-01T00:00:00Z",
+		1:  "1973-01-01T00:00:00Z",
+		22: "2006-01-01T00:00:00Z",
+		26: "2017-01-01T00:00:00Z",
+	}
+	for i, e := range expected {
+		if out := leaps[i].Format(time.RFC3339); out != e {
+			t.Errorf("leap second %d: got %v, expected %v", i, out, e)
+		}
+	}
+	for i := 1; i < len(leaps); i++ {
+		if !leaps[i-1].Before(leaps[i]) {
+			t.Errorf("expected %v to be before %v", leaps[i-1], leaps[i])
+		}
+	}
+
+	leaps[0] = time.Time{}
+	if LeapSeconds()[0].IsZero() {
+		t.Errorf("expected a copy of the leap seconds")
+	}
+}
+
+func TestTAItoUTC(t *testing.T) {
+	// before 1972 the offset is always 10 seconds
+	for _, utc := range []int64{-1 << 40, -10, 0, 63071999} {
+		if tai := UTCtoTAI(utc); tai != utc+10 {
+			t.Errorf("UTCtoTAI(%d): got %d, expected %d", utc, tai, utc+10)
+		}
+		if out := TAItoUTC(utc + 10); out != utc {
+			t.Errorf("TAItoUTC(%d): got %d, expected %d", utc+10, out, utc)
+		}
+	}
+
+	for i, leap := range LeapSeconds() {
+		utc := leap.Unix()
+		// the leap second itself, in TAI seconds
+		tai := utc + int64(i) + 10
+		tests := []struct {
+			tai, utc int64
+		}{
+			{tai - 1, utc - 1},
+			{tai, utc},
+			{tai + 1, utc},
+			{tai + 2, utc + 1},
+		}
+		for _, test := range tests {
+			if out := TAItoUTC(test.tai); out != test.utc {
+				t.Errorf("TAItoUTC(%d): got %d, expected %d", test.tai, out, test.utc)
+			}
+			if test.tai == tai {
+				continue
+			}
+			if out := UTCtoTAI(test.utc); out != test.tai {
+				t.Errorf("UTCtoTAI(%d): got %d, expected %d", test.utc, out, test.tai)
+			}
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected time.Duration
+	}{
+		{"2016-12-31T23:59:59Z", "2017-01-01T00:00:00Z", 2 * time.Second},
+		{"2016-12-31T23:59:59.5Z", "2017-01-01T00:00:00.25Z", 1750 * time.Millisecond},
+		{"2017-01-01T00:00:00Z", "2016-12-31T23:59:59Z", -2 * time.Second},
+		{"2016-12-31T23:59:58Z", "2016-12-31T23:59:59Z", time.Second},
+		{"2017-01-01T00:00:00Z", "2017-01-01T00:00:01Z", time.Second},
+		// 1972 had two leap seconds
+		{"1972-01-01T00:00:00Z", "1973-01-01T00:00:00Z", (366*86400 + 2) * time.Second},
+		{"1960-01-01T00:00:00Z", "1970-01-01T00:00:00Z", 3653 * 86400 * time.Second},
+	}
+	for _, test := range tests {
+		a, _ := time.Parse(time.RFC3339Nano, test.a)
+		b, _ := time.Parse(time.RFC3339Nano, test.b)
+		if out := Between(a, b); out != test.expected {
+			t.Errorf("Between(%v, %v): got %v, expected %v", test.a, test.b, out, test.expected)
+		}
+	}
+}
+
+func TestTaiSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		secs int64
+	}{
+		{0, 0},
+		{time.Second, 1},
+		{1750 * time.Millisecond, 1},
+		{-1750 * time.Millisecond, -1},
+		{24 * time.Hour, 86400},
+	}
+	for _, test := range tests {
+		if out := TaiSeconds(test.d); out != test.secs {
+			t.Errorf("TaiSeconds(%v): got %v, expected %v", test.d, out, test.secs)
+		}
+	}
+	if out := DurationFromTaiSeconds(86400); out != 24*time.Hour {
+		t.Errorf("got %v, expected %v", out, 24*time.Hour)
+	}
+
+	// across the leap second at the end of 2016 TAI seconds differ from UTC
+	a := time.Date(2016, 12, 31, 23, 59, 0, 0, time.UTC)
+	b := time.Date(2017, 1, 1, 0, 1, 0, 0, time.UTC)
+	secs := UTCtoTAI(b.Unix()) - UTCtoTAI(a.Unix())
+	if secs != 121 {
+		t.Errorf("got %v, expected %v", secs, 121)
+	}
+	if out := Between(a, b); out != DurationFromTaiSeconds(secs) {
+		t.Errorf("got %v, expected %v", out, DurationFromTaiSeconds(secs))
+	}
+	if out := TaiSeconds(Between(a, b)); out != secs {
+		t.Errorf("got %v, expected %v", out, secs)
+	}
+	if out := TaiSeconds(b.Sub(a)); out != 120 {
+		t.Errorf("got %v, expected %v", out, 120)
+	}
+}
+
+func TestOffsetAt(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"1960-01-01T00:00:00Z", 10 * time.Second},
+		{"1970-01-01T00:00:00Z", 10 * time.Second},
+		{"1972-06-30T23:59:59Z", 10 * time.Second},
+		{"1972-07-01T00:00:00Z", 11 * time.Second},
+		{"1999-08-24T04:03:43.7874925Z", 32 * time.Second},
+		{"2008-12-31T23:59:59Z", 33 * time.Second},
+		{"2009-01-01T00:00:00Z", 34 * time.Second},
+		{"2016-12-31T23:59:59.999999999Z", 36 * time.Second},
+		{"2017-01-01T00:00:00Z", 37 * time.Second},
+		{"2026-01-01T00:00:00Z", 37 * time.Second},
+	}
+	for _, test := range tests {
+		in, _ := time.Parse(time.RFC3339Nano, test.in)
+		if out := OffsetAt(in); out != test.expected {
+			t.Errorf("%v: got %v, expected %v", test.in, out, test.expected)
+		}
+	}
+}
+
+func TestSortLeapSeconds(t *testing.T) {
+	in := []int64{63072009, 1483228836, 78796810, 1435708835, 78796810}
+	expected := []int64{63072009, 78796810, 1435708835, 1483228836}
+	if out := sortLeapSeconds(in); !reflect.DeepEqual(out, expected) {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+
+	defer restoreLeapSeconds()()
+	// a hypothetical leap second at the end of June 2025, added out of order
+	setLeapSeconds(sortLeapSeconds(append(append([]int64{}, leapSeconds...), 1751328037)), 0)
+
+	tests := []struct {
+		utc string
+		tai int64
+	}{
+		{"2020-01-01T00:00:00Z", 1577836800 + 37},
+		{"2025-06-30T23:59:59Z", 1751327999 + 37},
+		{"2025-07-01T00:00:00Z", 1751328000 + 38},
+		{"2030-01-01T00:00:00Z", 1893456000 + 38},
+	}
+	for _, test := range tests {
+		utc, _ := time.Parse(time.RFC3339, test.utc)
+		if out := UTCtoTAI(utc.Unix()); out != test.tai {
+			t.Errorf("UTCtoTAI(%v): got %d, expected %d", test.utc, out, test.tai)
+		}
+		if out := EpochTime(test.tai, 0); !out.Equal(utc) {
+			t.Errorf("EpochTime(%d): got %v, expected %v", test.tai, out, test.utc)
+		}
+	}
+	if !isLeapSecond(1751328037) {
+		t.Errorf("expected 2025-06-30T23:59:60Z to be a leap second")
+	}
+}
+
+func BenchmarkEpochTime(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		EpochTime(1<<30, 0)
+	}
+}
+
+func BenchmarkEpochTimeLargeTable(b *testing.B) {
+	defer restoreLeapSeconds()()
+	// a leap second every six months for 500 years
+	table := make([]int64, 1000)
+	for i := range table {
+		table[i] = 63072009 + int64(i)*15778800
+	}
+	setLeapSeconds(sortLeapSeconds(table), 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EpochTime(1<<30, 0)
+	}
+}
+
+// tzif returns a TZif file with the given version and leap second records,
+// each an occurrence and a correction.
+func tzif(version byte, leaps [][2]int64) []byte {
+	var b bytes.Buffer
+	block := func(tsize int) {
+		b.WriteString("TZif")
+		b.WriteByte(version)
+		b.Write(make([]byte, 15))
+		// isutcnt, isstdcnt, leapcnt, timecnt, typecnt, charcnt
+		for _, n := range []uint32{0, 0, uint32(len(leaps)), 0, 1, 4} {
+			binary.Write(&b, binary.BigEndian, n)
+		}
+		// one local time type, UTC
+		b.Write([]byte{0, 0, 0, 0, 0, 0})
+		b.WriteString("UTC\x00")
+		for _, l := range leaps {
+			if tsize == 4 {
+				binary.Write(&b, binary.BigEndian, int32(l[0]))
+			} else {
+				binary.Write(&b, binary.BigEndian, l[0])
+			}
+			binary.Write(&b, binary.BigEndian, int32(l[1]))
+		}
+	}
+	block(4)
+	if version >= '2' {
+		block(8)
+		b.WriteString("\nUTC0\n")
+	}
+	return b.Bytes()
+}
+
+func TestLoadLeapSecondsFromZoneinfo(t *testing.T) {
+	defer restoreLeapSeconds()()
+	dir := t.TempDir()
+	write := func(b []byte) string {
+		path := filepath.Join(dir, "zone")
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// the leap seconds at the end of June 1972 and of 1972
+	leaps := [][2]int64{{78796800, 1}, {94694401, 2}}
+	expected := []int64{63072009, 78796810, 94694411}
+	for _, version := range []byte{0, '2', '3', '4'} {
+		setLeapSeconds(nil, 0)
+		if err := LoadLeapSecondsFromZoneinfo(write(tzif(version, leaps))); err != nil {
+			t.Errorf("version %q: expected nil error, got %v", version, err)
+		}
+		// without an expiry record the table never expires
+		if !LeapSecondsExpiry().IsZero() || LeapSecondsExpired() {
+			t.Errorf("version %q: got expiry %v, expected none", version, LeapSecondsExpiry())
+		}
+		if !reflect.DeepEqual(defaultConverter().table, expected) {
+			t.Errorf("version %q: got %v, expected %v", version, defaultConverter().table, expected)
+		}
+	}
+
+	// an expiry record
+	setLeapSeconds(nil, 0)
+	expiring := append(leaps, [2]int64{126230402, 2})
+	if err := LoadLeapSecondsFromZoneinfo(write(tzif('4', expiring))); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if !reflect.DeepEqual(defaultConverter().table, expected) {
+		t.Errorf("got %v, expected %v", defaultConverter().table, expected)
+	}
+	if out := LeapSecondsExpiry(); !out.Equal(time.Date(1974, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v, expected 1974-01-01", out)
+	}
+
+	// count returns a copy of b with count i of the header at offset set to n
+	count := func(b []byte, offset, i int, n uint32) []byte {
+		b = append([]byte(nil), b...)
+		binary.BigEndian.PutUint32(b[offset+20+4*i:], n)
+		return b
+	}
+	v1len := len(tzif('1', leaps))
+	bad := []struct {
+		in  []byte
+		err string
+	}{
+		{[]byte("TZif"), "tai64: invalid zoneinfo file"},
+		{count(tzif('1', leaps), 0, 2, 0xffffffff), "tai64: invalid zoneinfo file"},
+		{count(tzif('1', leaps), 0, 3, 0x80000000), "tai64: invalid zoneinfo file"},
+		{count(tzif('2', leaps), 0, 5, 0xffffffff), "tai64: invalid zoneinfo file"},
+		{count(tzif('2', leaps), v1len, 2, 0xffffffff), "tai64: invalid zoneinfo file"},
+		{count(tzif('2', leaps), v1len, 2, 100), "tai64: invalid zoneinfo file"},
+		{[]byte("not a tzif file at all, but long enough to have a header"), "tai64: invalid zoneinfo file"},
+		{tzif('2', leaps)[:60], "tai64: invalid zoneinfo file"},
+		{tzif('2', leaps)[:100], "tai64: invalid zoneinfo file"},
+		{tzif('2', [][2]int64{{78796800, 1}, {94694401, 3}}), "tai64: invalid zoneinfo file"},
+		{tzif('2', [][2]int64{{94694401, 1}, {78796800, 2}}), "tai64: invalid zoneinfo file"},
+		{tzif('2', nil), "tai64: no leap seconds found"},
+	}
+	for _, test := range bad {
+		setLeapSeconds(expected, 0)
+		err := LoadLeapSecondsFromZoneinfo(write(test.in))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("expected %v, got %v", test.err, err)
+		}
+		if !reflect.DeepEqual(defaultConverter().table, expected) {
+			t.Errorf("expected leap seconds to be unchanged, got %v", defaultConverter().table)
+		}
+	}
+
+	if err := LoadLeapSecondsFromZoneinfo(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func FuzzTzifLeapSeconds(f *testing.F) {
+	leaps := [][2]int64{{78796800, 1}, {94694401, 2}}
+	for _, version := range []byte{'1', '2', '4'} {
+		f.Add(tzif(version, leaps))
+	}
+	f.Add(tzif('2', nil))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		table, _, err := tzifLeapSeconds(b)
+		if err != nil {
+			if !errors.Is(err, ErrParse) {
+				t.Errorf("expected %v to match %v", err, ErrParse)
+			}
+			return
+		}
+		for i := 1; i < len(table); i++ {
+			if table[i] <= table[i-1] {
+				t.Fatalf("leap seconds out of order: %v", table)
+			}
+		}
+	})
+}
+
+func TestLoadLeapSecondsFromSystemZoneinfo(t *testing.T) {
+	path := "/usr/share/zoneinfo/right/UTC"
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("%s not found", path)
+	}
+	defer restoreLeapSeconds()()
+	builtin := leapSeconds
+	if err := LoadLeapSecondsFromZoneinfo(path); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	n := len(builtin)
+	if len(defaultConverter().table) < n || !reflect.DeepEqual(defaultConverter().table[:n], builtin) {
+		t.Errorf("got %v, expected it to start with %v", defaultConverter().table, builtin)
+	}
+}
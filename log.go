@@ -0,0 +1,222 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ConvertLog copies r to w, replacing any TAI64N label at the start of a line
+// with the time formatted using layout in loc. This is the format written by
+// multilog and read by tai64nlocal. Lines without a label, including lines
+// with an invalid label, are copied unchanged, as are line endings.
+func ConvertLog(r io.Reader, w io.Writer, layout string, loc *time.Location) error {
+	return ConvertLogContext(context.Background(), r, w, layout, loc)
+}
+
+// checkEvery is the number of lines ConvertLogContext converts between checks
+// for cancellation.
+const checkEvery = 100
+
+// ConvertLogContext is like ConvertLog but stops early, returning ctx.Err(), if
+// ctx is done. The context is checked every few lines, so some output may be
+// written after cancellation.
+func ConvertLogContext(ctx context.Context, r io.Reader, w io.Writer, layout string, loc *time.Location) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for n := 0; ; n++ {
+		if n%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				bw.Flush()
+				return err
+			}
+		}
+		line, err := br.ReadBytes('\n')
+		if len(line) >= Tai64nHexLen {
+			if t, perr := ParseTai64nBytes(line[:Tai64nHexLen]); perr == nil {
+				bw.WriteString(t.In(loc).Format(layout))
+				line = line[Tai64nHexLen:]
+			}
+		}
+		bw.Write(line)
+		// flush whenever we're waiting for input, so output isn't delayed
+		if br.Buffered() == 0 {
+			if ferr := bw.Flush(); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// LogScanner reads lines written by multilog, splitting each into a time and
+// a message. Successive calls to Scan step through the lines of the input.
+type LogScanner struct {
+	s    *bufio.Scanner
+	time time.Time
+	text string
+}
+
+// NewLogScanner returns a new LogScanner to read from r.
+func NewLogScanner(r io.Reader) *LogScanner {
+	return &LogScanner{s: bufio.NewScanner(r)}
+}
+
+// Scan advances to the next line, which is then available through Time and
+// Text. It returns false when there are no more lines or an error occurs.
+func (s *LogScanner) Scan() bool {
+	s.time, s.text = time.Time{}, ""
+	if !s.s.Scan() {
+		return false
+	}
+	line := s.s.Bytes()
+	if len(line) >= Tai64nHexLen {
+		if t, err := ParseTai64nBytes(line[:Tai64nHexLen]); err == nil {
+			s.time = t
+			line = line[Tai64nHexLen:]
+			// multilog separates the label and the message with a space
+			if len(line) > 0 && line[0] == ' ' {
+				line = line[1:]
+			}
+		}
+	}
+	s.text = string(line)
+	return true
+}
+
+// Time returns the time of the most recent line read by Scan, or the zero time
+// if the line did not start with a TAI64N label.
+func (s *LogScanner) Time() time.Time {
+	return s.time
+}
+
+// Text returns the message of the most recent line read by Scan. This is the
+// line without its label and the space following it, or the whole line if it
+// did not start with a TAI64N label.
+func (s *LogScanner) Text() string {
+	return s.text
+}
+
+// Err returns the first error encountered by the LogScanner.
+func (s *LogScanner) Err() error {
+	return s.s.Err()
+}
+
+// LogWriter writes lines in the format produced by multilog, with each line
+// prefixed by a TAI64N label and a space. A line may be written over several
+// calls to Write, only the first has the label added.
+type LogWriter struct {
+	// W is the writer that labeled lines are written to.
+	W io.Writer
+	// Clock returns the time used for labels. If it is nil time.Now is used.
+	Clock func() time.Time
+
+	midLine bool
+}
+
+// Write writes p to w.W, adding a label to the start of each line. If w.W
+// only writes part of the labeled lines, n is the number of bytes of p that
+// were written, not counting labels, and the next Write carries on from there
+// without labeling the same line twice.
+func (w *LogWriter) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	clock := w.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	label := FormatTai64n(clock())
+	var buf bytes.Buffer
+	midLine := w.midLine
+	for rest := p; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		if !midLine {
+			buf.WriteString(label)
+			buf.WriteByte(' ')
+		}
+		buf.Write(line)
+		midLine = line[len(line)-1] != '\n'
+		rest = rest[len(line):]
+	}
+	written, err := w.W.Write(buf.Bytes())
+	if written == buf.Len() {
+		w.midLine = midLine
+		return len(p), err
+	}
+	if err == nil {
+		err = io.ErrShortWrite
+	}
+	// work out how much of p made it, walking the lines as above
+	midLine = w.midLine
+	for rest := p; len(rest) > 0 && written > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		if !midLine {
+			// even part of a label starts the line, so it is not labeled again
+			if written -= len(label) + 1; written < 0 {
+				written = 0
+			}
+			midLine = true
+		}
+		k := len(line)
+		if written < k {
+			k = written
+		}
+		if k > 0 {
+			midLine = line[k-1] != '\n'
+		}
+		written -= k
+		n += k
+		rest = rest[len(line):]
+	}
+	w.midLine = midLine
+	return n, err
+}
+
+// ParseTai64nFilename parses the name of a log file rotated by multilog,
+// s6-log or svlogd, such as "@4000000037c219bf2ef02e94.s", into the time it
+// was rotated. Any directory is removed from name, as is a ".s" suffix for a
+// file that was rotated cleanly or a ".u" suffix for one that may be
+// incomplete. If the name is not a label an Error is returned.
+func ParseTai64nFilename(name string) (time.Time, error) {
+	name = filepath.Base(name)
+	if strings.HasSuffix(name, ".s") || strings.HasSuffix(name, ".u") {
+		name = name[:len(name)-2]
+	}
+	return ParseTai64n(name)
+}
+
+// IsRotatedLog reports whether name is a log file that was rotated cleanly by
+// multilog, s6-log or svlogd: a label with a ".s" suffix, such as
+// "@4000000037c219bf2ef02e94.s". Any directory is ignored.
+func IsRotatedLog(name string) bool {
+	name = filepath.Base(name)
+	return strings.HasSuffix(name, ".s") && ValidTai64n(name[:len(name)-2])
+}
+
+// IsCurrentLog reports whether name is a log file that is not yet complete,
+// so should be skipped by tools that ship finished logs. This is "current",
+// the file the logger is writing to, or a label with a ".u" suffix, which
+// svlogd uses for a file it did not finish writing. Any directory is ignored.
+func IsCurrentLog(name string) bool {
+	name = filepath.Base(name)
+	return name == "current" || strings.HasSuffix(name, ".u") && ValidTai64n(name[:len(name)-2])
+}
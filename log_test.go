@@ -0,0 +1,297 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertLog(t *testing.T) {
+	long := strings.Repeat("x", 100000)
+	in := "@4000000037c219bf2ef02e94 first line\n" +
+		"no timestamp\r\n" +
+		"@4000000052c65e550cd675fc crlf\r\n" +
+		"@4000000043b9410600000000" + long + "\n" +
+		"\n" +
+		"@4000000037c219bf2ef02e9 too short\n" +
+		"@4000000037c219bf2ef02e94"
+	expected := "1999-08-24T04:03:43.7874925Z first line\n" +
+		"no timestamp\r\n" +
+		"2014-01-03T06:52:34.2153815Z crlf\r\n" +
+		"2006-01-02T15:04:05Z" + long + "\n" +
+		"\n" +
+		"@4000000037c219bf2ef02e9 too short\n" +
+		"1999-08-24T04:03:43.7874925Z"
+
+	var out bytes.Buffer
+	if err := ConvertLog(strings.NewReader(in), &out, time.RFC3339Nano, time.UTC); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("got %q, expected %q", out.String(), expected)
+	}
+}
+
+// cancelReader calls cancel on its second read.
+type cancelReader struct {
+	r      io.Reader
+	reads  int
+	cancel func()
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads == 2 {
+		r.cancel()
+	}
+	return r.r.Read(p)
+}
+
+func TestConvertLogContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	line := "@4000000037c219bf2ef02e94 line\n"
+	// the first read returns just the first line
+	r := &cancelReader{
+		r:      io.MultiReader(strings.NewReader(line), strings.NewReader(strings.Repeat(line, 10*checkEvery))),
+		cancel: cancel,
+	}
+
+	var out bytes.Buffer
+	err := ConvertLogContext(ctx, r, &out, time.RFC3339Nano, time.UTC)
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if !strings.HasPrefix(out.String(), "1999-08-24T04:03:43.7874925Z line\n") {
+		t.Errorf("expected the first line to be converted, got %.50q", out.String())
+	}
+	if lines := strings.Count(out.String(), "\n"); lines > checkEvery {
+		t.Errorf("converted %d lines after cancellation", lines)
+	}
+
+	// a context that is already done stops before the first line
+	out.Reset()
+	err = ConvertLogContext(ctx, strings.NewReader(line), &out, time.RFC3339Nano, time.UTC)
+	if err != context.Canceled || out.Len() != 0 {
+		t.Errorf("got %q, %v", out.String(), err)
+	}
+}
+
+func TestLogScanner(t *testing.T) {
+	in := "@4000000037c219bf2ef02e94 first line\n" +
+		"no timestamp\n" +
+		"@4000000052c65e550cd675fc\n" +
+		"@4000000037c219bf2ef02e9 too short\n" +
+		"@4000000043b9410600000000  two spaces"
+	expected := []struct {
+		time string
+		text string
+	}{
+		{"1999-08-24T04:03:43.7874925Z", "first line"},
+		{"", "no timestamp"},
+		{"2014-01-03T06:52:34.2153815Z", ""},
+		{"", "@4000000037c219bf2ef02e9 too short"},
+		{"2006-01-02T15:04:05Z", " two spaces"},
+	}
+
+	s := NewLogScanner(strings.NewReader(in))
+	for i, e := range expected {
+		if !s.Scan() {
+			t.Fatalf("line %d: expected Scan to return true", i)
+		}
+		out := ""
+		if !s.Time().IsZero() {
+			out = s.Time().UTC().Format(time.RFC3339Nano)
+		}
+		if out != e.time {
+			t.Errorf("line %d: got time %v, expected %v", i, out, e.time)
+		}
+		if s.Text() != e.text {
+			t.Errorf("line %d: got text %q, expected %q", i, s.Text(), e.text)
+		}
+	}
+	if s.Scan() {
+		t.Errorf("expected Scan to return false")
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	now, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	w := &LogWriter{W: &out, Clock: func() time.Time { return now }}
+
+	writes := []string{
+		"first line\n",
+		"second line\nthird",
+		" line\n",
+		"",
+		"\n",
+		"no newline",
+	}
+	for _, in := range writes {
+		n, err := w.Write([]byte(in))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if n != len(in) {
+			t.Errorf("wrote %d bytes, expected %d", n, len(in))
+		}
+		// later lines get a later time
+		now = now.Add(time.Second)
+	}
+
+	expected := "@4000000037c219bf2ef02e94 first line\n" +
+		"@4000000037c219c02ef02e94 second line\n" +
+		"@4000000037c219c02ef02e94 third line\n" +
+		"@4000000037c219c32ef02e94 \n" +
+		"@4000000037c219c42ef02e94 no newline"
+	if out.String() != expected {
+		t.Errorf("got %q, expected %q", out.String(), expected)
+	}
+}
+
+// shortWriter writes at most n bytes to w, then fails.
+type shortWriter struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if len(p) <= s.n {
+		s.n -= len(p)
+		return s.w.Write(p)
+	}
+	n, _ := s.w.Write(p[:s.n])
+	s.n = 0
+	return n, s.err
+}
+
+func TestLogWriterShortWrite(t *testing.T) {
+	now, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	label := "@4000000037c219bf2ef02e94 "
+	errFull := errors.New("disk full")
+	tests := []struct {
+		limit int
+		in    string
+		n     int
+		err   error
+		// what is written when the rest of p is written afterwards
+		out string
+	}{
+		// part way through the label
+		{0, "first\nsecond\n", 0, errFull, label + "first\n" + label + "second\n"},
+		{10, "first\nsecond\n", 0, errFull, label[:10] + "first\n" + label + "second\n"},
+		{len(label), "first\nsecond\n", 0, errFull, label + "first\n" + label + "second\n"},
+		// part way through a line
+		{len(label) + 3, "first\nsecond\n", 3, errFull, label + "first\n" + label + "second\n"},
+		// at the end of a line, and part way through the next label
+		{len(label) + 6, "first\nsecond\n", 6, errFull, label + "first\n" + label + "second\n"},
+		{len(label) + 8, "first\nsecond\n", 6, errFull, label + "first\n" + label[:2] + "second\n"},
+		{len(label) + 9 + len(label), "first\nsecond\n", 9, errFull, label + "first\n" + label + "second\n"},
+		// a writer that stops without an error
+		{len(label) + 3, "first\n", 3, nil, label + "first\n"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		sw := &shortWriter{&out, test.limit, test.err}
+		w := &LogWriter{W: sw, Clock: func() time.Time { return now }}
+		n, err := w.Write([]byte(test.in))
+		if n != test.n {
+			t.Errorf("%d: wrote %d bytes, expected %d", test.limit, n, test.n)
+		}
+		expected := test.err
+		if expected == nil {
+			expected = io.ErrShortWrite
+		}
+		if err != expected {
+			t.Errorf("%d: got %v, expected %v", test.limit, err, expected)
+		}
+		// carry on with the rest once the writer has room
+		sw.n = 100
+		if _, err := w.Write([]byte(test.in[n:])); err != nil {
+			t.Fatalf("%d: expected nil error, got %v", test.limit, err)
+		}
+		if out.String() != test.out {
+			t.Errorf("%d: got %q, expected %q", test.limit, out.String(), test.out)
+		}
+	}
+}
+
+func TestParseTai64nFilename(t *testing.T) {
+	tests := []struct {
+		in   string
+		time string
+		err  error
+	}{
+		{"@4000000037c219bf2ef02e94.s", "1999-08-24T04:03:43.7874925Z", nil},
+		{"@4000000037c219bf2ef02e94.u", "1999-08-24T04:03:43.7874925Z", nil},
+		{"@4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", nil},
+		{"/var/log/app/@4000000037c219bf2ef02e94.s", "1999-08-24T04:03:43.7874925Z", nil},
+		{"log/@4000000037c219bf2ef02e94.u", "1999-08-24T04:03:43.7874925Z", nil},
+		{"current", "", ErrLength},
+		{"lock", "", ErrLength},
+		{"@4000000037c219bf2ef02e94.s.gz", "", ErrLength},
+		{"@4000000037c219bf2ef02e94.x", "", ErrLength},
+		{"x4000000037c219bf2ef02e94.s", "", ErrSyntax},
+		{"@4000000037c219bf2ef02e94/current", "", ErrLength},
+		{".s", "", ErrLength},
+		{"", "", ErrLength},
+	}
+	for _, test := range tests {
+		result, err := ParseTai64nFilename(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.err, err)
+		}
+		if test.err != nil {
+			continue
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.time)
+		}
+	}
+}
+
+func TestIsCurrentLog(t *testing.T) {
+	tests := []struct {
+		in      string
+		current bool
+		rotated bool
+	}{
+		{"@4000000037c219bf2ef02e94.s", false, true},
+		{"@4000000037c219bf2ef02e94.u", true, false},
+		{"/var/log/app/@4000000037c219bf2ef02e94.s", false, true},
+		{"log/@4000000037c219bf2ef02e94.u", true, false},
+		{"current", true, false},
+		{"/var/log/app/current", true, false},
+		{"@4000000037c219bf2ef02e94", false, false},
+		{"app.log", false, false},
+		{"lock", false, false},
+		{"state", false, false},
+		{"@4000000037c219bf2ef02e94.s.gz", false, false},
+		{"@4000000037c219bf2ef02e9z.s", false, false},
+		{"@f000000037c219bf2ef02e94.u", false, false},
+		{"current.u", false, false},
+		{".s", false, false},
+		{".u", false, false},
+		{"", false, false},
+	}
+	for _, test := range tests {
+		if out := IsCurrentLog(test.in); out != test.current {
+			t.Errorf("IsCurrentLog(%q): got %v, expected %v", test.in, out, test.current)
+		}
+		if out := IsRotatedLog(test.in); out != test.rotated {
+			t.Errorf("IsRotatedLog(%q): got %v, expected %v", test.in, out, test.rotated)
+		}
+	}
+}
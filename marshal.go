@@ -0,0 +1,264 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MarshalText implements the encoding.TextMarshaler interface. The text is the
+// hex TAI64N string, or empty for the zero Tai64n.
+func (l Tai64n) MarshalText() ([]byte, error) {
+	if l.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. Empty text
+// is unmarshaled as the zero Tai64n.
+func (l *Tai64n) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*l = Tai64n{}
+		return nil
+	}
+	v, err := ParseTai64nLabel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The data is
+// in the 12 byte binary external TAI64N format.
+func (l Tai64n) MarshalBinary() ([]byte, error) {
+	return l.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (l *Tai64n) UnmarshalBinary(data []byte) error {
+	v, err := DecodeTai64nLabel(data)
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface. The attribute
+// value is the hex TAI64N string. The zero Tai64n is omitted.
+func (l Tai64n) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if l.IsZero() {
+		return xml.Attr{}, nil
+	}
+	return xml.Attr{Name: name, Value: l.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface. An empty
+// attribute is unmarshaled as the zero Tai64n.
+func (l *Tai64n) UnmarshalXMLAttr(attr xml.Attr) error {
+	return l.UnmarshalText([]byte(attr.Value))
+}
+
+// MarshalXML implements the xml.Marshaler interface. The element contains the
+// hex TAI64N string, or is empty for the zero Tai64n.
+func (l Tai64n) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	text, _ := l.MarshalText()
+	return e.EncodeElement(string(text), start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. An empty element is
+// unmarshaled as the zero Tai64n.
+func (l *Tai64n) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	return l.UnmarshalText([]byte(s))
+}
+
+// WriteTo implements the io.WriterTo interface. It writes the 12 byte binary
+// external TAI64N format to w.
+func (l Tai64n) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(l.Bytes())
+	return int64(n), err
+}
+
+// ReadFrom implements the io.ReaderFrom interface. It reads exactly 12 bytes
+// in binary external TAI64N format from r. If r ends before all 12 bytes have
+// been read io.ErrUnexpectedEOF is returned.
+func (l *Tai64n) ReadFrom(r io.Reader) (int64, error) {
+	var b [Tai64nLen]byte
+	n, err := io.ReadFull(r, b[:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return int64(n), err
+	}
+	v, err := DecodeTai64nLabel(b[:])
+	if err != nil {
+		return int64(n), err
+	}
+	*l = v
+	return int64(n), nil
+}
+
+// Scan implements the sql.Scanner interface. It accepts the hex TAI64N string
+// as a string or []byte. NULL and empty values are scanned as the zero Tai64n.
+func (l *Tai64n) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = Tai64n{}
+		return nil
+	case string:
+		return l.UnmarshalText([]byte(v))
+	case []byte:
+		return l.UnmarshalText(v)
+	}
+	return Error{fmt.Sprintf("tai64: cannot scan %T into Tai64n", src), ""}
+}
+
+// Value implements the driver.Valuer interface. The value is the hex TAI64N
+// string, or NULL for the zero Tai64n.
+func (l Tai64n) Value() (driver.Value, error) {
+	if l.IsZero() {
+		return nil, nil
+	}
+	return l.String(), nil
+}
+
+// Tai64nRFC3339 is a Tai64n that is marshaled to JSON as an RFC 3339 UTC time
+// with nanoseconds, such as "1999-08-24T04:03:43.7874925Z", instead of a hex
+// TAI64N string. As that format cannot represent leap seconds, a label within a
+// leap second is marshaled as the second after it.
+type Tai64nRFC3339 Tai64n
+
+// MarshalJSON implements the json.Marshaler interface. The zero Tai64nRFC3339
+// is marshaled as an empty string.
+func (l Tai64nRFC3339) MarshalJSON() ([]byte, error) {
+	if Tai64n(l).IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(Tai64n(l).Time().UTC().Format(time.RFC3339Nano))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts any RFC
+// 3339 time. An empty string is unmarshaled as the zero Tai64nRFC3339.
+func (l *Tai64nRFC3339) UnmarshalJSON(data []byte) error {
+	// by convention null is a no-op
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*l = Tai64nRFC3339{}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*l = Tai64nRFC3339(NewTai64n(t))
+	return nil
+}
+
+// Tai64nUnixNano is a Tai64n that is marshaled to JSON as an integer number of
+// nanoseconds since the unix epoch, not counting leap seconds, instead of a hex
+// TAI64N string. An int64 can only represent times between the years 1678 and
+// 2262, and JavaScript numbers are only exact to within a microsecond or so
+// for present day times. A label within a leap second is marshaled as the
+// second after it.
+type Tai64nUnixNano Tai64n
+
+// MarshalJSON implements the json.Marshaler interface. The zero Tai64nUnixNano
+// is marshaled as null. If the time cannot be represented ErrRange is
+// returned.
+func (l Tai64nUnixNano) MarshalJSON() ([]byte, error) {
+	if Tai64n(l).IsZero() {
+		return []byte("null"), nil
+	}
+	t := Tai64n(l).Time()
+	n := t.UnixNano()
+	if !time.Unix(0, n).Equal(t) {
+		return nil, ErrRange
+	}
+	return json.Marshal(n)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (l *Tai64nUnixNano) UnmarshalJSON(data []byte) error {
+	// by convention null is a no-op
+	if string(data) == "null" {
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*l = Tai64nUnixNano(NewTai64n(time.Unix(0, n)))
+	return nil
+}
+
+// CSVField formats t for a CSV file as an RFC 3339 UTC time with nanoseconds,
+// which is readable by most spreadsheet and analysis tools.
+func CSVField(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseCSVField parses a CSV field written by CSVField, or a hex TAI64N string
+// if the field starts with '@'.
+func ParseCSVField(s string) (time.Time, error) {
+	if HasLabelPrefix(s) {
+		return ParseTai64n(s)
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// MarshalTagged returns t in a self describing binary format, for streams that
+// mix TAI64 and TAI64N labels. It is a single byte giving the length of the
+// label, 8 or 12, followed by the label in binary external TAI64N format if
+// withNanos is true, or TAI64 format otherwise.
+func MarshalTagged(t time.Time, withNanos bool) []byte {
+	if withNanos {
+		return append([]byte{Tai64nLen}, EncodeTai64n(t)...)
+	}
+	return append([]byte{Tai64Len}, EncodeTai64(t)...)
+}
+
+// UnmarshalTagged decodes a label written by MarshalTagged from the start of b,
+// and returns the number of bytes it used. If b is too short io.ErrUnexpectedEOF
+// is returned, and if the tag is not 8 or 12 an Error.
+func UnmarshalTagged(b []byte) (time.Time, int, error) {
+	if len(b) == 0 {
+		return time.Time{}, 0, io.ErrUnexpectedEOF
+	}
+	n := int(b[0])
+	if n != Tai64Len && n != Tai64nLen {
+		return time.Time{}, 0, Error{fmt.Sprintf("tai64: invalid tag %d, expected 8 or 12", b[0]), ErrSyntax.message}
+	}
+	if len(b) < 1+n {
+		return time.Time{}, 0, io.ErrUnexpectedEOF
+	}
+	var t time.Time
+	var err error
+	if n == Tai64nLen {
+		t, err = DecodeTai64n(b[1 : 1+n])
+	} else {
+		t, err = DecodeTai64(b[1 : 1+n])
+	}
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return t, 1 + n, nil
+}
@@ -0,0 +1,432 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTai64nJSON(t *testing.T) {
+	type record struct {
+		Time Tai64n `json:"time"`
+	}
+	for _, test := range tai64nTests {
+		in, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		b, err := json.Marshal(record{in})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		expected := `{"time":"` + FormatTai64n(in.Time()) + `"}`
+		if string(b) != expected {
+			t.Errorf("got %s, expected %s", b, expected)
+		}
+		var out record
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out.Time != in {
+			t.Errorf("got %v, expected %v", out.Time, in)
+		}
+	}
+
+	// empty strings are the zero value
+	out := record{Tai64n{1 << 62, 1}}
+	if err := json.Unmarshal([]byte(`{"time":""}`), &out); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !out.Time.IsZero() {
+		t.Errorf("expected zero value, got %v", out.Time)
+	}
+	if b, _ := json.Marshal(out); string(b) != `{"time":""}` {
+		t.Errorf("got %s, expected empty time", b)
+	}
+
+	err := json.Unmarshal([]byte(`{"time":"@4000000037c219bf"}`), &out)
+	if err == nil || !strings.Contains(err.Error(), ErrLength.Error()) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestTai64nRFC3339JSON(t *testing.T) {
+	type record struct {
+		Time Tai64nRFC3339 `json:"time"`
+	}
+	for _, test := range tai64nTests {
+		in, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		b, err := json.Marshal(record{Tai64nRFC3339(in)})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		expected := `{"time":"` + test.time + `"}`
+		if string(b) != expected {
+			t.Errorf("got %s, expected %s", b, expected)
+		}
+		var out record
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if Tai64n(out.Time) != in {
+			t.Errorf("got %v, expected %v", Tai64n(out.Time), in)
+		}
+	}
+
+	// other time zones are accepted
+	var out record
+	if err := json.Unmarshal([]byte(`{"time":"1999-08-23T21:03:43.7874925-07:00"}`), &out); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if s := Tai64n(out.Time).String(); s != "@4000000037c219bf2ef02e94" {
+		t.Errorf("got %v", s)
+	}
+
+	// empty strings are the zero value
+	if err := json.Unmarshal([]byte(`{"time":""}`), &out); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !Tai64n(out.Time).IsZero() {
+		t.Errorf("expected zero value, got %v", Tai64n(out.Time))
+	}
+	if b, _ := json.Marshal(out); string(b) != `{"time":""}` {
+		t.Errorf("got %s, expected empty time", b)
+	}
+
+	if err := json.Unmarshal([]byte(`{"time":"@4000000037c219bf2ef02e94"}`), &out); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestTai64nUnixNanoJSON(t *testing.T) {
+	type record struct {
+		Time Tai64nUnixNano `json:"time"`
+	}
+	for _, test := range tai64nTests {
+		in, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		b, err := json.Marshal(record{Tai64nUnixNano(in)})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		tm, _ := time.Parse(time.RFC3339Nano, test.time)
+		expected := fmt.Sprintf(`{"time":%d}`, tm.UnixNano())
+		if string(b) != expected {
+			t.Errorf("got %s, expected %s", b, expected)
+		}
+		var out record
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if Tai64n(out.Time) != in {
+			t.Errorf("got %v, expected %v", Tai64n(out.Time), in)
+		}
+	}
+
+	var out record
+	if err := json.Unmarshal([]byte(`{"time":935467423787492500}`), &out); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if s := Tai64n(out.Time).String(); s != "@4000000037c219bf2ef02e94" {
+		t.Errorf("got %v", s)
+	}
+
+	// null is a no-op, and the zero value is marshaled as null
+	if err := json.Unmarshal([]byte(`{"time":null}`), &out); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if s := Tai64n(out.Time).String(); s != "@4000000037c219bf2ef02e94" {
+		t.Errorf("got %v", s)
+	}
+	if b, _ := json.Marshal(record{}); string(b) != `{"time":null}` {
+		t.Errorf("got %s, expected null time", b)
+	}
+
+	// times outside the range of an int64 number of nanoseconds
+	for _, tm := range []time.Time{
+		time.Date(1677, 9, 21, 0, 0, 0, 0, time.UTC),
+		time.Date(2262, 4, 12, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := json.Marshal(record{Tai64nUnixNano(NewTai64n(tm))}); !errors.Is(err, ErrRange) {
+			t.Errorf("%v: expected %v, got %v", tm, ErrRange, err)
+		}
+	}
+
+	for _, in := range []string{`{"time":"935467423787492500"}`, `{"time":1.5}`, `{"time":"@4000000037c219bf2ef02e94"}`} {
+		if err := json.Unmarshal([]byte(in), &out); err == nil {
+			t.Errorf("%v: expected error, got nil", in)
+		}
+	}
+}
+
+func TestTai64nGob(t *testing.T) {
+	type record struct {
+		Time Tai64n
+	}
+	for _, test := range tai64nTests {
+		in, err := DecodeTai64nLabel(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(record{in}); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		var out record
+		if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out.Time != in {
+			t.Errorf("got %v, expected %v", out.Time, in)
+		}
+	}
+
+	var l Tai64n
+	if err := l.UnmarshalBinary([]byte{0x40, 0x00}); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestTai64nXML(t *testing.T) {
+	type record struct {
+		Attr Tai64n `xml:"time,attr"`
+		Elem Tai64n `xml:"time"`
+	}
+	for _, test := range tai64nTests {
+		in, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		data, err := xml.Marshal(record{in, in})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		hex := strings.ToLower(test.hex)
+		expected := `<record time="` + hex + `"><time>` + hex + `</time></record>`
+		if string(data) != expected {
+			t.Errorf("got %s, expected %s", data, expected)
+		}
+		var out record
+		if err := xml.Unmarshal(data, &out); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out.Attr != in || out.Elem != in {
+			t.Errorf("got %v, expected %v", out, in)
+		}
+	}
+
+	data, err := xml.Marshal(record{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if expected := `<record><time></time></record>`; string(data) != expected {
+		t.Errorf("got %s, expected %s", data, expected)
+	}
+	out := record{Tai64n{1, 1}, Tai64n{1, 1}}
+	if err := xml.Unmarshal([]byte(`<record time=""><time></time></record>`), &out); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if out != (record{}) {
+		t.Errorf("got %v, expected zero values", out)
+	}
+
+	for _, in := range []string{
+		`<record time="@4000000037c219bf"></record>`,
+		`<record><time>@4000000037c219bf</time></record>`,
+	} {
+		if err := xml.Unmarshal([]byte(in), &out); !errors.Is(err, ErrLength) {
+			t.Errorf("%v: expected %v, got %v", in, ErrLength, err)
+		}
+	}
+}
+
+func TestTai64nWriteToReadFrom(t *testing.T) {
+	var buf bytes.Buffer
+	for _, test := range tai64nTests {
+		in, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		n, err := in.WriteTo(&buf)
+		if err != nil || n != 12 {
+			t.Fatalf("got %v, %v, expected 12, nil", n, err)
+		}
+	}
+	for _, test := range tai64nTests {
+		var out Tai64n
+		n, err := out.ReadFrom(&buf)
+		if err != nil || n != 12 {
+			t.Fatalf("got %v, %v, expected 12, nil", n, err)
+		}
+		if out.String() != strings.ToLower(test.hex) {
+			t.Errorf("got %v, expected %v", out, test.hex)
+		}
+	}
+
+	tests := []struct {
+		in  []byte
+		n   int64
+		err error
+	}{
+		{nil, 0, io.ErrUnexpectedEOF},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x37}, 5, io.ErrUnexpectedEOF},
+		{[]byte{0xf0, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e, 0x94}, 12, ErrRange},
+	}
+	for _, test := range tests {
+		out := Tai64n{1, 1}
+		n, err := out.ReadFrom(bytes.NewBuffer(test.in))
+		if n != test.n || !errors.Is(err, test.err) {
+			t.Errorf("%x: got %v, %v, expected %v, %v", test.in, n, err, test.n, test.err)
+		}
+		if out != (Tai64n{1, 1}) {
+			t.Errorf("%x: expected Tai64n to be unchanged, got %v", test.in, out)
+		}
+	}
+}
+
+func TestTai64nSQL(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		v, err := in.Value()
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if v != in.String() {
+			t.Errorf("got %v, expected %v", v, in.String())
+		}
+		for _, src := range []interface{}{test.hex, []byte(test.hex)} {
+			var out Tai64n
+			if err := out.Scan(src); err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+			if out != in {
+				t.Errorf("got %v, expected %v", out, in)
+			}
+		}
+	}
+
+	out := Tai64n{1 << 62, 1}
+	if err := out.Scan(nil); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if !out.IsZero() {
+		t.Errorf("expected zero value, got %v", out)
+	}
+	if v, err := out.Value(); v != nil || err != nil {
+		t.Errorf("expected nil value, got %v, %v", v, err)
+	}
+
+	if err := out.Scan("@4000000037c219bf"); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+	if err := out.Scan(int64(1)); !errors.Is(err, ErrParse) {
+		t.Errorf("expected Error, got %v", err)
+	}
+}
+
+func TestCSVField(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		field := CSVField(in)
+		if field != test.time {
+			t.Errorf("got %v, expected %v", field, test.time)
+		}
+		for _, s := range []string{field, test.hex} {
+			out, err := ParseCSVField(s)
+			if err != nil {
+				t.Errorf("%v: expected nil error, got %v", s, err)
+			}
+			if !out.Equal(in) {
+				t.Errorf("%v: got %v, expected %v", s, out, in)
+			}
+		}
+	}
+
+	for _, s := range []string{"", "@4000000037c219bf", "4000000037c219bf2ef02e94", "1999-08-24 04:03:43Z"} {
+		if _, err := ParseCSVField(s); err == nil {
+			t.Errorf("%q: expected error, got nil", s)
+		}
+	}
+	if _, err := ParseCSVField("@4000000037c219bf"); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestTagged(t *testing.T) {
+	var buf []byte
+	var expected []time.Time
+	for _, test := range tai64nTests {
+		in, err := DecodeTai64n(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		b := MarshalTagged(in, true)
+		if !bytes.Equal(b, append([]byte{12}, test.bytes...)) {
+			t.Errorf("got %x, expected 0c%x", b, test.bytes)
+		}
+		buf = append(buf, b...)
+		expected = append(expected, in)
+
+		b = MarshalTagged(in, false)
+		if !bytes.Equal(b, append([]byte{8}, test.bytes[:8]...)) {
+			t.Errorf("got %x, expected 08%x", b, test.bytes[:8])
+		}
+		buf = append(buf, b...)
+		expected = append(expected, in.Truncate(time.Second))
+	}
+
+	for i := 0; len(buf) > 0; i++ {
+		out, n, err := UnmarshalTagged(buf)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if !out.Equal(expected[i]) {
+			t.Errorf("got %v, expected %v", out, expected[i])
+		}
+		buf = buf[n:]
+	}
+
+	tests := []struct {
+		in  []byte
+		err error
+	}{
+		{nil, io.ErrUnexpectedEOF},
+		{[]byte{12}, io.ErrUnexpectedEOF},
+		{[]byte{12, 0x40, 0, 0, 0, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e}, io.ErrUnexpectedEOF},
+		{[]byte{8, 0x40, 0, 0, 0, 0x37, 0xc2, 0x19}, io.ErrUnexpectedEOF},
+		{[]byte{16, 0x40, 0, 0, 0, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e, 0x94, 0, 0, 0, 0}, ErrSyntax},
+		{[]byte{0}, ErrSyntax},
+		{[]byte{8, 0x80, 0, 0, 0, 0, 0, 0, 0}, ErrRange},
+		{[]byte{12, 0x40, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, ErrRange},
+	}
+	for _, test := range tests {
+		out, n, err := UnmarshalTagged(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
+		}
+		if n != 0 || !out.IsZero() {
+			t.Errorf("%x: got %v, %v, expected nothing", test.in, out, n)
+		}
+	}
+}
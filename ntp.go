@@ -0,0 +1,32 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import "time"
+
+// ntpEpoch is the number of seconds between the NTP epoch (1900) and the unix
+// epoch (1970).
+const ntpEpoch = 2208988800
+
+// ToNTP returns t as a 64 bit NTP timestamp, with the seconds since 1900 in
+// the high 32 bits and the fraction of a second in the low 32 bits. The
+// fraction is rounded to the nearest 2^-32 of a second.
+//
+// Like unix time, NTP timestamps do not count leap seconds, so both a leap
+// second and the second after it have the same NTP timestamp. Times outside
+// NTP era 0, from 1900 to 2036, wrap around.
+func ToNTP(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpoch)
+	frac := (uint64(t.Nanosecond())<<32 + 5e8) / 1e9
+	return secs<<32 + frac
+}
+
+// FromNTP returns the time.Time for the 64 bit NTP timestamp n in NTP era 0.
+// The fraction of a second is rounded to the nearest nanosecond. See ToNTP for
+// details of how leap seconds are handled.
+func FromNTP(n uint64) time.Time {
+	secs := int64(n>>32) - ntpEpoch
+	nsecs := (n&0xffffffff*1e9 + 1<<31) >> 32
+	return time.Unix(secs, int64(nsecs))
+}
@@ -0,0 +1,47 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNTP(t *testing.T) {
+	tests := []struct {
+		tai64n string
+		ntp    uint64
+	}{
+		{"@4000000037c219bf2ef02e94", 0xbb6c981fc9991bc5},
+		{"@4000000052c65e550cd675fc", 0xd670dcb237233df3},
+		{UnixEpochTai64n, ntpEpoch << 32},
+		// the last second of 2016, a leap second and the second after it
+		{"@40000000586846a300000000", 0xdc12c4ff00000000},
+		{"@40000000586846a400000000", 0xdc12c50000000000},
+		{"@40000000586846a500000000", 0xdc12c50000000000},
+	}
+	for _, test := range tests {
+		in, err := ParseTai64n(test.tai64n)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := ToNTP(in); out != test.ntp {
+			t.Errorf("%v: got %x, expected %x", test.tai64n, out, test.ntp)
+		}
+		if out := FromNTP(test.ntp); !out.Equal(in) {
+			t.Errorf("%x: got %v, expected %v", test.ntp, out, in)
+		}
+	}
+
+	// nanoseconds are preserved through a round trip
+	for ns := 0; ns < 1e9; ns += 999 {
+		in := time.Unix(1e9, int64(ns))
+		if out := FromNTP(ToNTP(in)); !out.Equal(in) {
+			t.Fatalf("got %v, expected %v", out, in)
+		}
+	}
+	if out := FromNTP(ToNTP(time.Unix(1e9, 999999999))); !out.Equal(time.Unix(1e9, 999999999)) {
+		t.Errorf("got %v", out)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// Reader reads timestamps from a stream of labels in binary external TAI64N
+// format, packed one after another with no separator.
+type Reader struct {
+	r   io.Reader
+	buf [Tai64nLen]byte
+}
+
+// NewReader returns a Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads and decodes the next label. At the end of the stream it returns
+// io.EOF, or io.ErrUnexpectedEOF if the stream ends part way through a label.
+// If a label cannot be decoded an Error is returned, and the next call to Read
+// continues with the label after it.
+func (r *Reader) Read() (time.Time, error) {
+	if _, err := io.ReadFull(r.r, r.buf[:]); err != nil {
+		return time.Time{}, err
+	}
+	return DecodeTai64n(r.buf[:])
+}
+
+// ScanTai64n reads a hex TAI64N string, '@' followed by 24 hex digits, from rs
+// and parses it into a time.Time. rs is left after the label, so the caller can
+// continue reading whatever follows it.
+//
+// If the first rune is not '@' it is unread, so nothing is consumed, and an
+// ErrSyntax is returned. As a RuneScanner can only unread one rune, a label
+// that stops part way through is not fully unread: the runes before the
+// spurious one are consumed, and the spurious one is unread. If rs ends
+// before the first rune io.EOF is returned, or io.ErrUnexpectedEOF if it ends
+// part way through a label.
+func ScanTai64n(rs io.RuneScanner) (time.Time, error) {
+	var buf [Tai64nHexLen]byte
+	for i := range buf {
+		r, _, err := rs.ReadRune()
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if i == 0 && r != '@' {
+			rs.UnreadRune()
+			return time.Time{}, Error{fmt.Sprintf("tai64: invalid prefix %q at index 0, expected '@'", r), ErrSyntax.message}
+		}
+		if i > 0 && (r >= utf8.RuneSelf || nibbles[r] > 0xf) {
+			rs.UnreadRune()
+			return time.Time{}, Error{fmt.Sprintf("tai64: invalid hex %q at index %d", r, i), ErrSyntax.message}
+		}
+		buf[i] = byte(r)
+	}
+	return ParseTai64nBytes(buf[:])
+}
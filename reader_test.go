@@ -0,0 +1,109 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	var in []byte
+	for _, test := range tai64nTests[:3] {
+		in = append(in, test.bytes...)
+	}
+	r := NewReader(bytes.NewReader(in))
+	for _, test := range tai64nTests[:3] {
+		result, err := r.Read()
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if result, err := r.Read(); err != io.EOF || !result.IsZero() {
+			t.Errorf("got %v, %v, expected %v", result, err, io.EOF)
+		}
+	}
+}
+
+func TestReaderPartial(t *testing.T) {
+	in := append(append([]byte{}, tai64nTests[0].bytes...), tai64nTests[1].bytes[:5]...)
+	r := NewReader(bytes.NewReader(in))
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if result, err := r.Read(); err != io.ErrUnexpectedEOF || !result.IsZero() {
+		t.Errorf("got %v, %v, expected %v", result, err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	in := append([]byte{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, tai64nTests[0].bytes...)
+	r := NewReader(bytes.NewReader(in))
+	if _, err := r.Read(); !errors.Is(err, ErrRange) {
+		t.Errorf("expected %v, got %v", ErrRange, err)
+	}
+	result, err := r.Read()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if out := result.UTC().Format(time.RFC3339Nano); out != tai64nTests[0].time {
+		t.Errorf("got %v, expected %v", out, tai64nTests[0].time)
+	}
+}
+
+func TestScanTai64n(t *testing.T) {
+	tests := []struct {
+		in   string
+		time string
+		rest string
+		err  error
+	}{
+		{"@4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", "", nil},
+		{"@4000000037c219bf2ef02e94 hello", "1999-08-24T04:03:43.7874925Z", " hello", nil},
+		{"@4000000037C219BF2EF02E94deadbeef", "1999-08-24T04:03:43.7874925Z", "deadbeef", nil},
+		{"@4000000037c219bf2ef02e94@4000000052c65e550cd675fc", "1999-08-24T04:03:43.7874925Z", "@4000000052c65e550cd675fc", nil},
+		// nothing is consumed if the first rune does not match
+		{"hello", "", "hello", ErrSyntax},
+		{"é@4000000037c219bf2ef02e94", "", "é@4000000037c219bf2ef02e94", ErrSyntax},
+		// otherwise the scanner stops at the spurious rune
+		{"@4000000037c219bf hello", "", " hello", ErrSyntax},
+		{"@4000000037c219bfé", "", "é", ErrSyntax},
+		{"@f000000037c219bf2ef02e94 hello", "", " hello", ErrRange},
+		{"", "", "", io.EOF},
+		{"@4000000037c219bf", "", "", io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		r := strings.NewReader(test.in)
+		result, err := ScanTai64n(r)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.err, err)
+		}
+		rest, _ := io.ReadAll(r)
+		if string(rest) != test.rest {
+			t.Errorf("%q: got rest %q, expected %q", test.in, rest, test.rest)
+		}
+		if test.err != nil {
+			if !result.IsZero() {
+				t.Errorf("%q: expected zero time, got %v", test.in, result)
+			}
+			continue
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.time)
+		}
+	}
+
+	_, err := ScanTai64n(strings.NewReader("@4000000037c219bfé"))
+	if expected := `tai64: invalid hex 'é' at index 17`; err == nil || err.Error() != expected {
+		t.Errorf("got %v, expected %v", err, expected)
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+//go:build go1.21
+
+package tai64
+
+import "log/slog"
+
+// ReplaceAttr can be used as the ReplaceAttr function of slog.HandlerOptions
+// to log the time of each record as a hex TAI64N string, matching logs written
+// by multilog. Other attributes, and time attributes within groups, are
+// returned unchanged.
+func ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+		return slog.String(slog.TimeKey, FormatTai64n(a.Value.Time()))
+	}
+	return a
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+//go:build go1.21
+
+package tai64
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func ExampleReplaceAttr() {
+	h := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: ReplaceAttr})
+	t, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	t = t.UTC()
+	r := slog.NewRecord(t, slog.LevelInfo, "status: 1/40", 0)
+	r.AddAttrs(slog.Time("started", t))
+	h.Handle(context.Background(), r)
+	// Output: time=@4000000037c219bf2ef02e94 level=INFO msg="status: 1/40" started=1999-08-24T04:03:43.787Z
+}
+
+func TestReplaceAttr(t *testing.T) {
+	in, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	tests := []struct {
+		groups []string
+		attr   slog.Attr
+		value  string
+	}{
+		{nil, slog.Time(slog.TimeKey, in), "@4000000037c219bf2ef02e94"},
+		{[]string{"request"}, slog.Time(slog.TimeKey, in), in.String()},
+		{nil, slog.Time("started", in), in.String()},
+		{nil, slog.String(slog.TimeKey, "now"), "now"},
+		{nil, slog.String(slog.MessageKey, "hello"), "hello"},
+	}
+	for _, test := range tests {
+		out := ReplaceAttr(test.groups, test.attr)
+		if out.Key != test.attr.Key || out.Value.String() != test.value {
+			t.Errorf("%v: got %v, expected %v=%v", test.attr, out, test.attr.Key, test.value)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// SmearDecode is like DecodeTai64n but smears each leap second over window,
+// for comparing labels with times from clocks that smear leap seconds instead
+// of inserting them. Google's public NTP servers use a 24 hour window.
+//
+// The smear is linear and centered on the leap second. It starts window/2
+// before the leap second was inserted in UTC, and ends window/2 after, so a
+// 24 hour window runs from noon to noon UTC. Within the smear the window plus
+// the leap second of TAI maps evenly onto the window of UTC, so UTC falls
+// behind TAI by one second over the window, and the middle of the leap second
+// is exactly the moment it was inserted. The result is truncated to the
+// nanosecond. Outside the smear, or if window is not positive, the result is
+// the same as DecodeTai64n. The window should be much shorter than the months
+// between leap seconds.
+//
+// If the data cannot be decoded an Error is returned.
+func SmearDecode(b []byte, window time.Duration) (time.Time, error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if window <= 0 {
+		return l.Time(), nil
+	}
+	secs := int64(l.Label - (1 << 62))
+	table := defaultConverter().table
+	// the smear is either for the next leap second or the previous one
+	n := sort.Search(len(table), func(i int) bool { return table[i] >= secs })
+	half := int64(window/time.Second/2) + 1
+	for _, i := range []int{n - 1, n} {
+		// the first entry is the initial 10 second offset, not a leap second
+		if i < 1 || i >= len(table) || secs < table[i]-half || secs > table[i]+half {
+			continue
+		}
+		// how far into the smear the label is, in TAI
+		d := time.Duration(secs-table[i])*time.Second + time.Duration(l.Nanoseconds) + window/2
+		if d < 0 || d >= window+time.Second {
+			continue
+		}
+		// entry i is i+9 seconds ahead of the unix time the leap second was
+		// inserted at
+		start := time.Unix(table[i]-int64(i+9), 0).Add(-window / 2)
+		hi, lo := bits.Mul64(uint64(d), uint64(window))
+		smeared, _ := bits.Div64(hi, lo, uint64(window+time.Second))
+		return start.Add(time.Duration(smeared)), nil
+	}
+	return l.Time(), nil
+}
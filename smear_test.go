@@ -0,0 +1,73 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSmearDecode(t *testing.T) {
+	// the leap second at the end of 2016, smeared from noon to noon UTC
+	tests := []struct {
+		secs  int64
+		nsecs uint32
+		time  string
+	}{
+		// before the smear
+		{1483185635, 999999999, "2016-12-31T11:59:59.999999999Z"},
+		// the start of the smear
+		{1483185636, 0, "2016-12-31T12:00:00Z"},
+		{1483185637, 0, "2016-12-31T12:00:00.999988426Z"},
+		// the start, middle and end of the leap second
+		{1483228836, 0, "2016-12-31T23:59:59.500005786Z"},
+		{1483228836, 500000000, "2017-01-01T00:00:00Z"},
+		{1483228836, 999999999, "2017-01-01T00:00:00.499994212Z"},
+		// the end of the smear
+		{1483272036, 999999999, "2017-01-01T11:59:59.999999999Z"},
+		{1483272037, 0, "2017-01-01T12:00:00Z"},
+		// far from any leap second, and before the first one
+		{1388731989, 215381500, "2014-01-03T06:52:34.2153815Z"},
+		{0, 0, "1969-12-31T23:59:50Z"},
+	}
+	for _, test := range tests {
+		in := Tai64n{uint64(test.secs) + 1<<62, test.nsecs}
+		result, err := SmearDecode(in.Bytes(), 24*time.Hour)
+		if err != nil {
+			t.Fatalf("%v: expected nil error, got %v", in, err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%v: got %v, expected %v", in, out, test.time)
+		}
+	}
+
+	// the smear is monotonic across the leap second, unlike DecodeTai64n
+	var prev time.Time
+	for ns := int64(0); ns < 3e9; ns += 1e8 {
+		in := Tai64n{1<<62 + 1483228835 + uint64(ns/1e9), uint32(ns % 1e9)}
+		result, err := SmearDecode(in.Bytes(), 24*time.Hour)
+		if err != nil {
+			t.Fatalf("%v: expected nil error, got %v", in, err)
+		}
+		if !result.After(prev) {
+			t.Errorf("%v: got %v, expected it to be after %v", in, result, prev)
+		}
+		prev = result
+	}
+
+	// without a window it is the same as DecodeTai64n
+	for _, test := range tai64nTests {
+		expected, _ := DecodeTai64n(test.bytes)
+		for _, window := range []time.Duration{0, -time.Hour} {
+			if result, err := SmearDecode(test.bytes, window); err != nil || !result.Equal(expected) {
+				t.Errorf("%x: got %v, %v, expected %v", test.bytes, result, err, expected)
+			}
+		}
+	}
+
+	if _, err := SmearDecode(tai64nTests[0].bytes[:8], time.Hour); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
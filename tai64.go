@@ -0,0 +1,239 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	hexDigits      = "0123456789abcdef"
+	upperHexDigits = "0123456789ABCDEF"
+)
+
+// The lengths in bytes of the binary external TAI64, TAI64N and TAI64NA
+// formats, and of their hex strings, which are '@' followed by two hex digits
+// per byte.
+const (
+	Tai64Len   = 8
+	Tai64nLen  = 12
+	Tai64naLen = 16
+
+	Tai64HexLen   = 1 + 2*Tai64Len
+	Tai64nHexLen  = 1 + 2*Tai64nLen
+	Tai64naHexLen = 1 + 2*Tai64naLen
+)
+
+// Tai64 is a TAI64 label. Unlike a time.Time it keeps the TAI second exactly,
+// so labels that fall within a leap second are distinct from the second after.
+type Tai64 struct {
+	// Label is the external TAI64 label, 2^62 plus the number of TAI seconds
+	// since the beginning of 1970 TAI.
+	Label uint64
+}
+
+// NewTai64 returns the Tai64 label of the second containing t. Any monotonic
+// clock reading in t, such as from time.Now, is stripped, so the label
+// depends only on the wall clock.
+func NewTai64(t time.Time) Tai64 {
+	return Tai64{label(t.Round(0))}
+}
+
+// ParseTai64Label parses a string containing a hex TAI64 string into a Tai64.
+// If the string cannot be parsed an Error is returned.
+func ParseTai64Label(s string) (Tai64, error) {
+	if len(s) != Tai64HexLen {
+		return Tai64{}, lengthError(len(s), Tai64HexLen)
+	}
+	if !isHexLabel(s) {
+		return Tai64{}, syntaxError(s)
+	}
+	sec := hexValue(s[1:])
+	// "Labels 2^63 and above are reserved for future extensions"
+	if !InFirstHalf(sec) {
+		return Tai64{}, rangeError("label", sec)
+	}
+	return Tai64{sec}, nil
+}
+
+// InFirstHalf reports whether the label sec is in the first half of the TAI64
+// label space, below 2^63. The TAI64 specification reserves labels of 2^63 and
+// above, including 2^63 itself, for future extensions, so every function in
+// this package rejects them with ErrRange.
+func InFirstHalf(sec uint64) bool {
+	return sec < 1<<63
+}
+
+// DecodeTai64Label decodes a timestamp in binary external TAI64 format into a
+// Tai64. If the data cannot be decoded an Error is returned.
+func DecodeTai64Label(b []byte) (Tai64, error) {
+	secs, err := DecodeLabel(b, 1<<62)
+	if err != nil {
+		return Tai64{}, err
+	}
+	return Tai64{uint64(secs) + 1<<62}, nil
+}
+
+// DecodeLabel decodes an 8 byte big endian label into a number of seconds by
+// subtracting bias. TAI64 labels have a bias of 2^62, but this can be used for
+// similar formats with a different bias. As with TAI64, labels of 2^63 and
+// above are rejected with ErrRange.
+func DecodeLabel(b []byte, bias uint64) (int64, error) {
+	if len(b) != Tai64Len {
+		return 0, lengthError(len(b), Tai64Len)
+	}
+	label := binary.BigEndian.Uint64(b)
+	// "Labels 2^63 and above are reserved for future extensions"
+	if !InFirstHalf(label) {
+		return 0, rangeError("label", label)
+	}
+	return int64(label - bias), nil
+}
+
+// Time returns the time.Time for l. Labels within a leap second return the
+// same time as the following second.
+func (l Tai64) Time() time.Time {
+	return EpochTime(int64(l.Label-(1<<62)), 0)
+}
+
+// IsLeapSecond reports whether l falls within an inserted leap second, such as
+// 2016-12-31T23:59:60Z. Time returns the following second for these labels.
+func (l Tai64) IsLeapSecond() bool {
+	return isLeapSecond(int64(l.Label - (1 << 62)))
+}
+
+// Less reports whether l is earlier than other. Labels are compared in TAI, so
+// a leap second is earlier than the second after it, even though both have the
+// same Time.
+func (l Tai64) Less(other Tai64) bool {
+	return l.Label < other.Label
+}
+
+// String returns l as a hex TAI64 string, such as "@4000000037c219bf".
+func (l Tai64) String() string {
+	return fmt.Sprintf("@%016x", l.Label)
+}
+
+// Bytes returns l in the 8 byte binary external TAI64 format.
+func (l Tai64) Bytes() []byte {
+	b := make([]byte, Tai64Len)
+	binary.BigEndian.PutUint64(b, l.Label)
+	return b
+}
+
+// Tai64n is a TAI64N label, a TAI64 label and a nanosecond counter. As with
+// Tai64 it keeps the TAI second exactly. The zero Tai64n represents an unset
+// value.
+type Tai64n struct {
+	// Label is the external TAI64 label, 2^62 plus the number of TAI seconds
+	// since the beginning of 1970 TAI.
+	Label uint64
+	// Nanoseconds is the nanosecond counter, between 0 and 999999999.
+	Nanoseconds uint32
+}
+
+// NewTai64n returns the Tai64n label for t. As with NewTai64 any monotonic
+// clock reading is stripped.
+func NewTai64n(t time.Time) Tai64n {
+	t = t.Round(0)
+	return Tai64n{label(t), uint32(t.Nanosecond())}
+}
+
+// ParseTai64nLabel parses a string containing a hex TAI64N string into a
+// Tai64n. If the string cannot be parsed an Error is returned.
+func ParseTai64nLabel(s string) (Tai64n, error) {
+	// "A TAI64N label is normally stored or communicated in external TAI64N
+	// format, consisting of twelve 8-bit bytes", which is 24 chars of hex
+	if len(s) != Tai64nHexLen {
+		return Tai64n{}, lengthError(len(s), Tai64nHexLen)
+	}
+	if !isHexLabel(s) {
+		return Tai64n{}, syntaxError(s)
+	}
+	// "The first eight bytes are the TAI64 label", and "the last four bytes
+	// are the nanosecond counter in big-endian format"
+	sec := hexValue(s[1:Tai64HexLen])
+	nsec := uint32(hexValue(s[Tai64HexLen:Tai64nHexLen]))
+	if !InFirstHalf(sec) {
+		return Tai64n{}, rangeError("label", sec)
+	}
+	// "The nanosecond counter is an integer between 0 and 999999999"
+	if nsec >= 1e9 {
+		return Tai64n{}, rangeError("nanoseconds", uint64(nsec))
+	}
+	return Tai64n{sec, nsec}, nil
+}
+
+// DecodeTai64nLabel decodes a timestamp in binary external TAI64N format into
+// a Tai64n. If the data cannot be decoded an Error is returned.
+func DecodeTai64nLabel(b []byte) (Tai64n, error) {
+	if len(b) != Tai64nLen {
+		return Tai64n{}, lengthError(len(b), Tai64nLen)
+	}
+	sec := binary.BigEndian.Uint64(b[:Tai64Len])
+	nsec := binary.BigEndian.Uint32(b[Tai64Len:Tai64nLen])
+	if !InFirstHalf(sec) {
+		return Tai64n{}, rangeError("label", sec)
+	}
+	if nsec >= 1e9 {
+		return Tai64n{}, rangeError("nanoseconds", uint64(nsec))
+	}
+	return Tai64n{sec, nsec}, nil
+}
+
+// IsZero reports whether l is the zero Tai64n.
+func (l Tai64n) IsZero() bool {
+	return l == Tai64n{}
+}
+
+// Time returns the time.Time for l. Labels within a leap second return the
+// same time as the following second.
+func (l Tai64n) Time() time.Time {
+	return EpochTime(int64(l.Label-(1<<62)), int64(l.Nanoseconds))
+}
+
+// IsLeapSecond reports whether l falls within an inserted leap second, such as
+// 2016-12-31T23:59:60Z. Time returns the following second for these labels.
+func (l Tai64n) IsLeapSecond() bool {
+	return isLeapSecond(int64(l.Label - (1 << 62)))
+}
+
+// String returns l as a hex TAI64N string, such as
+// "@4000000037c219bf2ef02e94".
+func (l Tai64n) String() string {
+	return string(l.appendText(make([]byte, 0, Tai64nHexLen)))
+}
+
+// appendText appends the hex TAI64N string for l to dst.
+func (l Tai64n) appendText(dst []byte) []byte {
+	return l.appendHex(dst, hexDigits)
+}
+
+// appendHex appends the hex TAI64N string for l to dst, using digits for the
+// hex digits.
+func (l Tai64n) appendHex(dst []byte, digits string) []byte {
+	dst = append(dst, '@')
+	for i := 60; i >= 0; i -= 4 {
+		dst = append(dst, digits[l.Label>>uint(i)&0xf])
+	}
+	for i := 28; i >= 0; i -= 4 {
+		dst = append(dst, digits[l.Nanoseconds>>uint(i)&0xf])
+	}
+	return dst
+}
+
+// Bytes returns l in the 12 byte binary external TAI64N format.
+func (l Tai64n) Bytes() []byte {
+	return l.appendBinary(make([]byte, 0, Tai64nLen))
+}
+
+// appendBinary appends l in binary external TAI64N format to dst.
+func (l Tai64n) appendBinary(dst []byte) []byte {
+	var b [Tai64nLen]byte
+	binary.BigEndian.PutUint64(b[:Tai64Len], l.Label)
+	binary.BigEndian.PutUint32(b[Tai64Len:], l.Nanoseconds)
+	return append(dst, b[:]...)
+}
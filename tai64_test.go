@@ -0,0 +1,218 @@
+// Copyright 2014 Paul Hammond.
+// This software is licensed under the MIT license, see LICENSE.txt for details.
+
+package tai64
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLengths(t *testing.T) {
+	tests := []struct {
+		name     string
+		got      int
+		expected int
+	}{
+		{"Tai64Len", Tai64Len, 8},
+		{"Tai64nLen", Tai64nLen, 12},
+		{"Tai64naLen", Tai64naLen, 16},
+		{"Tai64HexLen", Tai64HexLen, 17},
+		{"Tai64nHexLen", Tai64nHexLen, 25},
+		{"Tai64naHexLen", Tai64naHexLen, 33},
+		// and the fixtures agree
+		{"len(tai64Tests[0].bytes)", len(tai64Tests[0].bytes), Tai64Len},
+		{"len(tai64nTests[0].bytes)", len(tai64nTests[0].bytes), Tai64nLen},
+		{"len(tai64naTests[0].bytes)", len(tai64naTests[0].bytes), Tai64naLen},
+		{"len(tai64Tests[0].hex)", len(tai64Tests[0].hex), Tai64HexLen},
+		{"len(tai64nTests[0].hex)", len(tai64nTests[0].hex), Tai64nHexLen},
+		{"len(tai64naTests[0].hex)", len(tai64naTests[0].hex), Tai64naHexLen},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("%s: got %d, expected %d", test.name, test.got, test.expected)
+		}
+	}
+}
+
+func TestTai64(t *testing.T) {
+	for _, test := range tai64Tests {
+		l, err := ParseTai64Label(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		d, err := DecodeTai64Label(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if l != d {
+			t.Errorf("parsed %v, decoded %v", l, d)
+		}
+		if n := NewTai64(l.Time()); n != l {
+			t.Errorf("got %v, expected %v", n, l)
+		}
+		if out := l.Time().UTC().Format(time.RFC3339); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+		if out := l.String(); out != strings.ToLower(test.hex) {
+			t.Errorf("got %v, expected %v", out, test.hex)
+		}
+		if out := l.Bytes(); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+	}
+}
+
+func TestDecodeLabel(t *testing.T) {
+	tests := []struct {
+		bytes []byte
+		bias  uint64
+		secs  int64
+		err   error
+	}{
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf}, 1 << 62, 0x37c219bf, nil},
+		{[]byte{0x3f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1 << 62, -1, nil},
+		// raw seconds since the unix epoch
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf}, 0, 0x37c219bf, nil},
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, 10, -10, nil},
+		{[]byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0, 1<<63 - 1, nil},
+		{[]byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, 0, 0, ErrRange},
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x00}, 0, 0, ErrLength},
+		{nil, 0, 0, ErrLength},
+	}
+	for _, test := range tests {
+		secs, err := DecodeLabel(test.bytes, test.bias)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.bytes, test.err, err)
+		}
+		if secs != test.secs {
+			t.Errorf("%x: got %d, expected %d", test.bytes, secs, test.secs)
+		}
+	}
+}
+
+func TestTai64LeapSecond(t *testing.T) {
+	// 2016-12-31T23:59:60 UTC and the second after it
+	leap := Tai64{1<<62 + 1483228836}
+	next := Tai64{1<<62 + 1483228837}
+	if leap == next {
+		t.Errorf("expected %v and %v to differ", leap, next)
+	}
+	if !leap.Time().Equal(next.Time()) {
+		t.Errorf("expected %v and %v to have the same time", leap, next)
+	}
+}
+
+func TestTai64Less(t *testing.T) {
+	// events logged at 2016-12-31T23:59:59Z, during the leap second and at
+	// 2017-01-01T00:00:00Z, in the wrong order
+	events := []struct {
+		name  string
+		label Tai64
+	}{
+		{"after", Tai64{1<<62 + 1483228837}},
+		{"leap", Tai64{1<<62 + 1483228836}},
+		{"before", Tai64{1<<62 + 1483228835}},
+	}
+	// the last two have the same UTC time, so sorting by time keeps them in
+	// the wrong order
+	byTime := append(events[:0:0], events...)
+	sort.SliceStable(byTime, func(i, j int) bool { return byTime[i].label.Time().Before(byTime[j].label.Time()) })
+	if byTime[1].name != "after" || byTime[2].name != "leap" {
+		t.Errorf("expected times to tie, got %v", byTime)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].label.Less(events[j].label) })
+	for i, name := range []string{"before", "leap", "after"} {
+		if events[i].name != name {
+			t.Errorf("%d: got %v, expected %v", i, events[i].name, name)
+		}
+	}
+
+	l := Tai64{1<<62 + 1483228836}
+	if l.Less(l) {
+		t.Errorf("expected %v not to be less than itself", l)
+	}
+}
+
+func TestIsLeapSecond(t *testing.T) {
+	tests := []struct {
+		hex  string
+		leap bool
+	}{
+		// 1992-06-30T23:59:59Z, 23:59:60Z and 1992-07-01T00:00:00Z
+		{"@400000002a50f599", false},
+		{"@400000002a50f59a", true},
+		{"@400000002a50f59b", false},
+		// 2016-12-31T23:59:59Z, 23:59:60Z and 2017-01-01T00:00:00Z
+		{"@40000000586846a3", false},
+		{"@40000000586846a4", true},
+		{"@40000000586846a5", false},
+		// 1972-01-01T00:00:00Z, when the offset was set to 10 seconds
+		{"@4000000003c26709", false},
+	}
+	for _, test := range tests {
+		l, err := ParseTai64Label(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := l.IsLeapSecond(); out != test.leap {
+			t.Errorf("%v: got %v, expected %v", test.hex, out, test.leap)
+		}
+		n := Tai64n{l.Label, 500000000}
+		if out := n.IsLeapSecond(); out != test.leap {
+			t.Errorf("%v: got %v, expected %v", n, out, test.leap)
+		}
+	}
+}
+
+func TestTai64n(t *testing.T) {
+	for _, test := range tai64nTests {
+		l, err := ParseTai64nLabel(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		d, err := DecodeTai64nLabel(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if l != d {
+			t.Errorf("parsed %v, decoded %v", l, d)
+		}
+		if n := NewTai64n(l.Time()); n != l {
+			t.Errorf("got %v, expected %v", n, l)
+		}
+		if out := l.Time().UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+		if out := l.String(); out != strings.ToLower(test.hex) {
+			t.Errorf("got %v, expected %v", out, test.hex)
+		}
+		if out := l.Bytes(); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+	}
+}
+
+func TestInFirstHalf(t *testing.T) {
+	tests := []struct {
+		in  uint64
+		out bool
+	}{
+		{0, true},
+		{1 << 62, true},
+		{1<<63 - 1, true},
+		// the boundary itself is the first reserved label
+		{1 << 63, false},
+		{1<<63 + 1, false},
+		{1<<64 - 1, false},
+	}
+	for _, test := range tests {
+		if out := InFirstHalf(test.in); out != test.out {
+			t.Errorf("%#x: got %v, expected %v", test.in, out, test.out)
+		}
+	}
+}
@@ -1,14 +1,17 @@
 // Copyright 2014 Paul Hammond.
 // This software is licensed under the MIT license, see LICENSE.txt for details.
 
-// Package tai64 implements conversion from the TAI64 and TAI64N formats. See
-// http://cr.yp.to/daemontools/tai64n.html and
+// Package tai64 implements conversion to and from the TAI64, TAI64N and TAI64NA
+// formats. See http://cr.yp.to/daemontools/tai64n.html and
 // http://cr.yp.to/libtai/tai64.html for more information on these formats.
 package tai64
 
 import (
 	"encoding/binary"
-	"strconv"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -17,6 +20,8 @@ import (
 // http://www.ietf.org/timezones/data/leap-seconds.list
 // http://hpiers.obspm.fr/eop-pc/earthor/utc/UTC.html
 // http://maia.usno.navy.mil/leapsec.html
+// It is sorted when the package is initialized, so new entries can be added
+// anywhere.
 var leapSeconds = []int64{
 	// subtract 2208988800 to convert from NTP datetime to unix seconds
 	// then add number of previous leap seconds to get TAI-since-unix-epoch
@@ -50,94 +55,977 @@ var leapSeconds = []int64{
 	63072009,
 }
 
-// Error is returned when parsing or decoding fails.
+// leapSecondsExpiry is when the table above should no longer be trusted, in
+// seconds since the unix epoch. It is the expiry date of the leap-seconds.list
+// file the table was last checked against, 28 June 2027. The IERS announces
+// every six months whether there will be a leap second, and a new file is
+// published with a later expiry, so the table and this date need updating at
+// least that often. Run the tests with TAI64_CHECK_LEAP_SECONDS set to check
+// that they are not stale.
+const leapSecondsExpiry = 1814140800
+
+// Labels for the beginning of 1970 TAI, and for the unix epoch, which is the
+// beginning of 1970 UTC and 10 seconds later.
+const (
+	TaiEpochTai64   = "@4000000000000000"
+	TaiEpochTai64n  = "@400000000000000000000000"
+	UnixEpochTai64  = "@400000000000000a"
+	UnixEpochTai64n = "@400000000000000a00000000"
+)
+
+// Error is returned when parsing or decoding fails. Its message describes
+// what failed, and it matches the sentinel error for its kind, so
+// errors.Is(err, ErrSyntax) works for any syntax error.
 type Error struct {
 	message string
+	kind    string
 }
 
 func (e Error) Error() string {
 	return e.message
 }
 
-var parseError = Error{"tai64 parse error"}
-var decodeError = Error{"tai64 decode error"}
+// Is reports whether e matches target. Every Error matches ErrParse, so
+// errors.Is(err, ErrParse) can be used to detect any failure from this package.
+// Errors that match ErrNanoseconds or ErrAttoseconds also match ErrRange.
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	if t == ErrParse || t == e || t.message == e.kind {
+		return true
+	}
+	return t == ErrRange && (e.kind == ErrNanoseconds.message || e.kind == ErrAttoseconds.message)
+}
+
+// ErrParse is a general parse error. It is not returned directly, but matches
+// every other Error.
+var ErrParse = Error{"tai64: parse error", ""}
+
+// ErrLength is returned when the input is the wrong length for its format.
+var ErrLength = Error{"tai64: invalid length", ""}
+
+// ErrSyntax is returned when a string is missing its leading '@' or contains
+// characters that are not hex digits.
+var ErrSyntax = Error{"tai64: invalid syntax", ""}
+
+// ErrRange is returned when a field of the input is outside its valid range.
+var ErrRange = Error{"tai64: value out of range", ""}
+
+// ErrNanoseconds is returned when the nanosecond counter of a TAI64N or
+// TAI64NA label is 10^9 or more. It matches ErrRange.
+var ErrNanoseconds = Error{"tai64: nanoseconds out of range", ErrRange.message}
+
+// ErrAttoseconds is returned when the attosecond counter of a TAI64NA label is
+// 10^9 or more. It matches ErrRange.
+var ErrAttoseconds = Error{"tai64: attoseconds out of range", ErrRange.message}
+
+// ErrNotFound is returned when a string does not contain a label.
+var ErrNotFound = Error{"tai64: label not found", ""}
+
+// ErrZeroTime is returned by FormatTai64nSafe when it is passed the zero
+// time.Time.
+var ErrZeroTime = Error{"tai64: zero time", ""}
+
+// lengthError returns an ErrLength describing the length of the input.
+func lengthError(got, want int) error {
+	return Error{fmt.Sprintf("tai64: invalid length %d, expected %d", got, want), ErrLength.message}
+}
+
+// prefixError returns an ErrSyntax for a string that does not start with '@'.
+func prefixError(c byte) error {
+	return Error{fmt.Sprintf("tai64: invalid prefix %q at index 0, expected '@'", c), ErrSyntax.message}
+}
+
+// hexError returns an ErrSyntax for the non hex digit c at index i.
+func hexError(c byte, i int) error {
+	return Error{fmt.Sprintf("tai64: invalid hex %q at index %d", c, i), ErrSyntax.message}
+}
+
+// rangeError returns an ErrRange for a field with the value v. The counters
+// have their own kinds, ErrNanoseconds and ErrAttoseconds.
+func rangeError(field string, v uint64) error {
+	kind := ErrRange
+	switch field {
+	case "nanoseconds":
+		kind = ErrNanoseconds
+	case "attoseconds":
+		kind = ErrAttoseconds
+	}
+	return Error{fmt.Sprintf("tai64: %s %#x out of range", field, v), kind.message}
+}
+
+// Parse parses a hex TAI64, TAI64N or TAI64NA string into a time.Time,
+// choosing the format from the length of s. If the string cannot be parsed an
+// Error is returned.
+func Parse(s string) (time.Time, error) {
+	// TAI64N is by far the most common, so it is checked first and decoded
+	// in a single pass. Invalid labels are left to ParseTai64n to describe.
+	if len(s) == Tai64nHexLen && s[0] == '@' {
+		var sec, nsec uint64
+		var bad byte
+		for i := 1; i < Tai64HexLen; i++ {
+			v := nibbles[s[i]]
+			bad |= v
+			sec = sec<<4 | uint64(v)
+		}
+		for i := Tai64HexLen; i < Tai64nHexLen; i++ {
+			v := nibbles[s[i]]
+			bad |= v
+			nsec = nsec<<4 | uint64(v)
+		}
+		if bad <= 0xf && InFirstHalf(sec) && nsec < 1e9 {
+			return EpochTime(int64(sec-(1<<62)), int64(nsec)), nil
+		}
+	}
+	switch len(s) {
+	case Tai64HexLen:
+		return ParseTai64(s)
+	case Tai64nHexLen:
+		return ParseTai64n(s)
+	case Tai64naHexLen:
+		return ParseTai64na(s)
+	}
+	return time.Time{}, Error{fmt.Sprintf("tai64: invalid length %d, expected 17, 25 or 33", len(s)), ErrLength.message}
+}
 
 // ParseTai64 parses a string containing a hex TAI64 string into a time.Time.
-// If the string cannot be parsed an Error is returned.
+// The hex digits may be upper or lower case. If the string cannot be parsed an
+// Error is returned.
 func ParseTai64(s string) (time.Time, error) {
-	if len(s) != 17 || s[0] != '@' {
-		return time.Time{}, parseError
-	}
-	sec, err := strconv.ParseUint(s[1:], 16, 64)
+	l, err := ParseTai64Label(s)
 	if err != nil {
-		return time.Time{}, parseError
+		return time.Time{}, err
+	}
+	return l.Time(), nil
+}
+
+// ParseTai64Padded is like ParseTai64 but also accepts a hex TAI64 string with
+// leading zeros removed, such as "@37c219bf", which is treated as if it were
+// padded to 16 hex digits. Note that labels near the present always have 16
+// digits, so a short label is usually a time long before 1970.
+func ParseTai64Padded(s string) (time.Time, error) {
+	if len(s) < 2 || len(s) > Tai64HexLen {
+		return time.Time{}, Error{fmt.Sprintf("tai64: invalid length %d, expected 2 to 17", len(s)), ErrLength.message}
 	}
-	if sec > 1<<63 {
-		return time.Time{}, parseError
+	if !isHexLabel(s) {
+		return time.Time{}, syntaxError(s)
 	}
-	return EpochTime(int64(sec-(1<<62)), 0), nil
+	sec := hexValue(s[1:])
+	if !InFirstHalf(sec) {
+		return time.Time{}, rangeError("label", sec)
+	}
+	return Tai64{sec}.Time(), nil
 }
 
 // ParseTai64n parses a string containing a hex TAI64N string into a
-// time.Time. If the string cannot be parsed an Error is returned.
+// time.Time. The hex digits may be upper or lower case. If the string cannot
+// be parsed an Error is returned.
 func ParseTai64n(s string) (time.Time, error) {
-	// "A TAI64N label is normally stored or communicated in external TAI64N
-	// format, consisting of twelve 8-bit bytes", which is 24 chars of hex
-	if len(s) != 25 || s[0] != '@' {
-		return time.Time{}, parseError
+	l, err := ParseTai64nLabel(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return l.Time(), nil
+}
+
+// ParseTai64nLenient is like ParseTai64n but ignores surrounding whitespace
+// and accepts strings without the leading '@'. It is the same as
+// ParseTai64nOpts with AllowWhitespace and AllowNoAt.
+func ParseTai64nLenient(s string) (time.Time, error) {
+	return ParseTai64nOpts(s, AllowWhitespace(), AllowNoAt())
+}
+
+// An Option changes how ParseTai64nOpts parses a string.
+type Option func(*parseOptions)
+
+type parseOptions struct {
+	noAt       bool
+	whitespace bool
+}
+
+// AllowNoAt returns an Option that accepts strings without the leading '@'.
+func AllowNoAt() Option {
+	return func(o *parseOptions) { o.noAt = true }
+}
+
+// AllowWhitespace returns an Option that ignores whitespace before and after
+// the label.
+func AllowWhitespace() Option {
+	return func(o *parseOptions) { o.whitespace = true }
+}
+
+// ParseTai64nOpts is like ParseTai64n but its strictness can be relaxed by
+// opts. With no options it is the same as ParseTai64n. The nanosecond counter
+// is always checked, as a time.Time cannot represent a counter of 10^9 or more.
+func ParseTai64nOpts(s string, opts ...Option) (time.Time, error) {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.whitespace {
+		s = strings.TrimSpace(s)
+	}
+	if o.noAt && !HasLabelPrefix(s) {
+		s = "@" + s
+	}
+	return ParseTai64n(s)
+}
+
+// ParseTai64nHex is like ParseTai64n but the 24 hex digits can be prefixed by
+// "0x", as some JSON encoders write them, by '@', or not prefixed at all.
+func ParseTai64nHex(s string) (time.Time, error) {
+	hex, ok := trimAt(s)
+	if !ok && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")) {
+		hex = s[2:]
+	}
+	if len(hex) != Tai64nHexLen-1 {
+		return time.Time{}, lengthError(len(s), len(s)-len(hex)+Tai64nHexLen-1)
+	}
+	return ParseTai64n("@" + hex)
+}
+
+// ParseTai64nBracketed is like ParseTai64n but also accepts a label wrapped in
+// a single pair of brackets, parentheses or angle brackets, as in
+// "[@4000000037c219bf2ef02e94]". A string that opens a bracket without closing
+// it with the same kind of bracket, or that has more than one pair, returns
+// an ErrSyntax.
+func ParseTai64nBracketed(s string) (time.Time, error) {
+	var closing byte
+	if len(s) > 0 {
+		switch s[0] {
+		case '[':
+			closing = ']'
+		case '(':
+			closing = ')'
+		case '<':
+			closing = '>'
+		}
+	}
+	if closing != 0 {
+		if len(s) < 2 || s[len(s)-1] != closing {
+			return time.Time{}, Error{fmt.Sprintf("tai64: unmatched bracket %q", s[0]), ErrSyntax.message}
+		}
+		s = s[1 : len(s)-1]
+		if s != "" && strings.IndexByte("[(<", s[0]) >= 0 {
+			return time.Time{}, Error{fmt.Sprintf("tai64: nested bracket %q", s[0]), ErrSyntax.message}
+		}
+	}
+	return ParseTai64n(s)
+}
+
+// ParseTai64nBytes is like ParseTai64n but parses a hex TAI64N string held in
+// a byte slice, avoiding the allocation of converting it to a string.
+func ParseTai64nBytes(b []byte) (time.Time, error) {
+	if len(b) != Tai64nHexLen {
+		return time.Time{}, lengthError(len(b), Tai64nHexLen)
 	}
-	// "The first eight bytes are the TAI64 label"
-	sec, err := strconv.ParseUint(s[1:17], 16, 64)
+	if b[0] != '@' {
+		return time.Time{}, prefixError(b[0])
+	}
+	sec, i := parseHex(b[1:Tai64HexLen])
+	if i >= 0 {
+		return time.Time{}, hexError(b[1+i], 1+i)
+	}
+	nsec, i := parseHex(b[Tai64HexLen:Tai64nHexLen])
+	if i >= 0 {
+		return time.Time{}, hexError(b[Tai64HexLen+i], Tai64HexLen+i)
+	}
+	if !InFirstHalf(sec) {
+		return time.Time{}, rangeError("label", sec)
+	}
+	if nsec >= 1e9 {
+		return time.Time{}, rangeError("nanoseconds", nsec)
+	}
+	return EpochTime(int64(sec-(1<<62)), int64(nsec)), nil
+}
+
+// SplitTai64n parses the hex TAI64N string at the start of s, which is '@'
+// followed by exactly 24 hex digits, and returns the rest of s after it. Unlike
+// ParseTai64n the label can be followed by anything, including more hex
+// digits, which are returned as part of rest. If s does not start with a label
+// an Error is returned.
+func SplitTai64n(s string) (t time.Time, rest string, err error) {
+	if len(s) < Tai64nHexLen {
+		return time.Time{}, "", Error{fmt.Sprintf("tai64: invalid length %d, expected at least 25", len(s)), ErrLength.message}
+	}
+	t, err = ParseTai64n(s[:Tai64nHexLen])
 	if err != nil {
-		return time.Time{}, parseError
+		return time.Time{}, "", err
+	}
+	return t, s[Tai64nHexLen:], nil
+}
+
+// SplitTai64nBinary decodes the binary external TAI64N label at the start of b
+// and returns the rest of b after it. It is the inverse of AppendTai64nBinary.
+// If b is shorter than 12 bytes or does not start with a valid label an Error
+// is returned.
+func SplitTai64nBinary(b []byte) (t time.Time, rest []byte, err error) {
+	if len(b) < Tai64nLen {
+		return time.Time{}, nil, Error{fmt.Sprintf("tai64: invalid length %d, expected at least 12", len(b)), ErrLength.message}
 	}
-	// "The last four bytes are the nanosecond counter in big-endian format"
-	nsec, err := strconv.ParseUint(s[17:25], 16, 32)
+	t, err = DecodeTai64n(b[:Tai64nLen])
 	if err != nil {
-		return time.Time{}, parseError
+		return time.Time{}, nil, err
+	}
+	return t, b[Tai64nLen:], nil
+}
+
+// FindTai64n finds the first valid hex TAI64N string within s and parses it
+// into a time.Time. It also returns the byte offsets of the label, so that it
+// is s[start:end]. If s does not contain a valid label ErrNotFound is
+// returned, with start and end both -1.
+func FindTai64n(s string) (t time.Time, start, end int, err error) {
+	for i := 0; i+Tai64nHexLen <= len(s); i++ {
+		j := strings.IndexByte(s[i:len(s)-Tai64nHexLen+1], '@')
+		if j < 0 {
+			break
+		}
+		i += j
+		if v, perr := ParseTai64n(s[i : i+Tai64nHexLen]); perr == nil {
+			return v, i, i + Tai64nHexLen, nil
+		}
+	}
+	return time.Time{}, -1, -1, ErrNotFound
+}
+
+// ParseAllTai64n finds every valid hex TAI64N string within s, in order, and
+// parses them into times. It returns nil if s does not contain a valid label.
+func ParseAllTai64n(s string) []time.Time {
+	var times []time.Time
+	for {
+		t, _, end, err := FindTai64n(s)
+		if err != nil {
+			return times
+		}
+		times = append(times, t)
+		s = s[end:]
+	}
+}
+
+// ParseTai64na parses a string containing a hex TAI64NA string into a
+// time.Time. The attosecond counter is checked but otherwise ignored, as a
+// time.Time cannot represent it. If the string cannot be parsed an Error is
+// returned.
+func ParseTai64na(s string) (time.Time, error) {
+	// a TAI64NA label is sixteen bytes, which is 32 chars of hex
+	if len(s) != Tai64naHexLen {
+		return time.Time{}, lengthError(len(s), Tai64naHexLen)
+	}
+	if !isHexLabel(s) {
+		return time.Time{}, syntaxError(s)
+	}
+	sec := hexValue(s[1:Tai64HexLen])
+	nsec := hexValue(s[Tai64HexLen:Tai64nHexLen])
+	// "the attosecond counter in big-endian format", which must be less
+	// than 10^9
+	asec := hexValue(s[Tai64nHexLen:Tai64naHexLen])
+	if !InFirstHalf(sec) {
+		return time.Time{}, rangeError("label", sec)
+	}
+	if nsec >= 1e9 {
+		return time.Time{}, rangeError("nanoseconds", nsec)
 	}
-	if sec > 1<<63 {
-		return time.Time{}, parseError
+	if asec >= 1e9 {
+		return time.Time{}, rangeError("attoseconds", asec)
 	}
 	return EpochTime(int64(sec-(1<<62)), int64(nsec)), nil
 }
 
+// Decode decodes a timestamp in binary external TAI64, TAI64N or TAI64NA
+// format into a time.Time, choosing the format from the length of b. If the
+// data cannot be decoded an Error is returned.
+func Decode(b []byte) (time.Time, error) {
+	switch len(b) {
+	case Tai64Len:
+		return DecodeTai64(b)
+	case Tai64nLen:
+		return DecodeTai64n(b)
+	case Tai64naLen:
+		return DecodeTai64na(b)
+	}
+	return time.Time{}, Error{fmt.Sprintf("tai64: invalid length %d, expected 8, 12 or 16", len(b)), ErrLength.message}
+}
+
 // DecodeTai64 decodes a timestamp in binary external TAI64 format into a
-// time.Time. If the data cannot be decoded an Error is returned.
+// time.Time. b must be exactly 8 bytes; a 12 byte TAI64N label is rejected with
+// ErrLength rather than having its nanoseconds ignored. Use DecodeTai64Exact
+// to also accept TAI64N labels that fall exactly on a second. If the data
+// cannot be decoded an Error is returned.
 func DecodeTai64(b []byte) (time.Time, error) {
-	if len(b) != 8 {
-		return time.Time{}, decodeError
+	secs, err := DecodeLabel(b, 1<<62)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return EpochTime(secs, 0), nil
+}
+
+// DecodeTai64Exact is like DecodeTai64 but also accepts a label in the 12 byte
+// binary external TAI64N format, for producers that send TAI64N to consumers
+// expecting whole seconds. An ErrRange is returned if the nanosecond counter
+// of a TAI64N label is not zero, as the fraction of a second would otherwise
+// be lost.
+func DecodeTai64Exact(b []byte) (time.Time, error) {
+	if len(b) != Tai64nLen {
+		return DecodeTai64(b)
 	}
-	sec := binary.BigEndian.Uint64(b)
-	if sec > 1<<63 {
-		return time.Time{}, decodeError
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if l.Nanoseconds != 0 {
+		return time.Time{}, Error{fmt.Sprintf("tai64: nanoseconds %#x not zero", l.Nanoseconds), ErrRange.message}
 	}
-	return EpochTime(int64(sec-(1<<62)), 0), nil
+	return l.Time(), nil
 }
 
 // DecodeTai64n decodes a timestamp in binary external TAI64N format into a
 // time.Time. If the data cannot be decoded an Error is returned.
 func DecodeTai64n(b []byte) (time.Time, error) {
-	if len(b) != 12 {
-		return time.Time{}, decodeError
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return l.Time(), nil
+}
+
+// DecodeTai64nStrict is like DecodeTai64n but also returns ErrRange if the
+// time is not between the years 1 and 9999 UTC, which most formats and
+// systems cannot represent.
+func DecodeTai64nStrict(b []byte) (time.Time, error) {
+	t, err := DecodeTai64n(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := checkYear(t); err != nil {
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// checkYear returns an ErrRange if t is not between the years 1 and 9999 UTC.
+func checkYear(t time.Time) error {
+	if y := t.UTC().Year(); y < 1 || y > 9999 {
+		return Error{fmt.Sprintf("tai64: year %d out of range", y), ErrRange.message}
+	}
+	return nil
+}
+
+// DecodeTai64nLocal is like DecodeTai64n but returns the time in loc. This
+// only changes the location used to display the time, the instant is the same.
+func DecodeTai64nLocal(b []byte, loc *time.Location) (time.Time, error) {
+	t, err := DecodeTai64n(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+// DecodeTai64nTAI is like DecodeTai64n but returns a time in the TAI time
+// scale instead of UTC. The result does not represent the correct instant;
+// formatting it in UTC shows the TAI calendar date and time, which is ahead of
+// UTC by 10 seconds plus the number of leap seconds. Use DecodeTai64n unless
+// you specifically need TAI.
+func DecodeTai64nTAI(b []byte) (time.Time, error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(l.Label-1<<62), int64(l.Nanoseconds)).UTC(), nil
+}
+
+// DecodeBoth decodes a timestamp in binary external TAI64N format into both
+// the UTC time returned by DecodeTai64n and the TAI time returned by
+// DecodeTai64nTAI, which is useful when debugging leap second handling. The two
+// differ by OffsetAt(utc), except within a leap second. If the data cannot be
+// decoded an Error is returned.
+func DecodeBoth(b []byte) (utc, tai time.Time, err error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	tai = time.Unix(int64(l.Label-1<<62), int64(l.Nanoseconds)).UTC()
+	return l.Time(), tai, nil
+}
+
+// DecodeTai64nWithOffset is like DecodeTai64n but also returns the number of
+// seconds subtracted to convert the label from TAI to UTC. This is 10 before the
+// first leap second in 1972, and one more for each leap second since, so a
+// change in offset between labels shows that they span a leap second. A label
+// within a leap second has the offset from before it, as it becomes the
+// following UTC second. If the data cannot be decoded an Error is returned.
+func DecodeTai64nWithOffset(b []byte) (t time.Time, offset int, err error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return time.Time{}, 0, err
 	}
-	sec := binary.BigEndian.Uint64(b[0:8])
-	nsec := binary.BigEndian.Uint32(b[8:12])
-	if sec > 1<<63 {
-		return time.Time{}, decodeError
+	secs := int64(l.Label - (1 << 62))
+	utc := TAItoUTC(secs)
+	return time.Unix(utc, int64(l.Nanoseconds)), int(secs - utc), nil
+}
+
+// DecodeTai64nInto is like DecodeTai64n but stores the decoded time in t,
+// which is left unchanged if the data cannot be decoded. It does not allocate,
+// so is suitable for decoding many timestamps in a loop.
+func DecodeTai64nInto(b []byte, t *time.Time) error {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return err
+	}
+	*t = l.Time()
+	return nil
+}
+
+// DecodeTai64nSlice decodes a sequence of timestamps in binary external TAI64N
+// format, packed one after another with no separator. The length of b must be
+// a multiple of 12. If any label cannot be decoded an Error including its
+// index is returned.
+func DecodeTai64nSlice(b []byte) ([]time.Time, error) {
+	if len(b)%Tai64nLen != 0 {
+		return nil, Error{fmt.Sprintf("tai64: invalid length %d, expected a multiple of 12", len(b)), ErrLength.message}
+	}
+	times := make([]time.Time, len(b)/Tai64nLen)
+	for i := range times {
+		if err := DecodeTai64nInto(b[i*Tai64nLen:(i+1)*Tai64nLen], &times[i]); err != nil {
+			var e Error
+			if !errors.As(err, &e) {
+				return nil, fmt.Errorf("tai64: label %d: %w", i, err)
+			}
+			kind := e.kind
+			if kind == "" {
+				// e is one of the sentinel errors
+				kind = e.message
+			}
+			return nil, Error{fmt.Sprintf("tai64: label %d: %s", i, strings.TrimPrefix(e.message, "tai64: ")), kind}
+		}
+	}
+	return times, nil
+}
+
+// DecodeTai64na decodes a timestamp in binary external TAI64NA format into a
+// time.Time. The attosecond counter is checked but otherwise ignored, as a
+// time.Time cannot represent it. If the data cannot be decoded an Error is
+// returned.
+func DecodeTai64na(b []byte) (time.Time, error) {
+	if len(b) != Tai64naLen {
+		return time.Time{}, lengthError(len(b), Tai64naLen)
+	}
+	sec := binary.BigEndian.Uint64(b[:Tai64Len])
+	nsec := binary.BigEndian.Uint32(b[Tai64Len:Tai64nLen])
+	asec := binary.BigEndian.Uint32(b[Tai64nLen:Tai64naLen])
+	if !InFirstHalf(sec) {
+		return time.Time{}, rangeError("label", sec)
+	}
+	if nsec >= 1e9 {
+		return time.Time{}, rangeError("nanoseconds", uint64(nsec))
+	}
+	if asec >= 1e9 {
+		return time.Time{}, rangeError("attoseconds", uint64(asec))
 	}
 	return EpochTime(int64(sec-(1<<62)), int64(nsec)), nil
 }
 
+// DecodeTai64naStrict is like DecodeTai64na but also returns ErrRange if the
+// time is not between the years 1 and 9999 UTC, as DecodeTai64nStrict does.
+// A nanosecond or attosecond counter of 10^9 or more returns ErrNanoseconds or
+// ErrAttoseconds, so a misread label can be told apart from one that is simply
+// too far in the past or future.
+func DecodeTai64naStrict(b []byte) (time.Time, error) {
+	t, err := DecodeTai64na(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := checkYear(t); err != nil {
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// nibbles maps hex digits to their value, and every other byte to 0xff.
+var nibbles = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xff
+	}
+	for i := byte(0); i < 10; i++ {
+		t['0'+i] = i
+	}
+	for i := byte(0); i < 6; i++ {
+		t['a'+i] = 10 + i
+		t['A'+i] = 10 + i
+	}
+	return t
+}()
+
+// parseHex parses b, which must be no more than 16 hex digits, into an integer.
+// If b contains a character that is not a hex digit its index is returned,
+// otherwise the index is -1.
+func parseHex(b []byte) (uint64, int) {
+	var n uint64
+	for i, c := range b {
+		v := nibbles[c]
+		if v > 0xf {
+			return 0, i
+		}
+		n = n<<4 | uint64(v)
+	}
+	return n, -1
+}
+
+// HasLabelPrefix reports whether s starts with '@', as every hex TAI64, TAI64N
+// and TAI64NA string does.
+func HasLabelPrefix(s string) bool {
+	return len(s) > 0 && s[0] == '@'
+}
+
+// trimAt returns s without its leading '@', and whether it had one. If it did
+// not s is returned unchanged.
+func trimAt(s string) (string, bool) {
+	if !HasLabelPrefix(s) {
+		return s, false
+	}
+	return s[1:], true
+}
+
+// isHexLabel reports whether s is an '@' followed by one or more hex digits,
+// in upper or lower case.
+func isHexLabel(s string) bool {
+	hex, ok := trimAt(s)
+	if !ok || hex == "" {
+		return false
+	}
+	for i := 0; i < len(hex); i++ {
+		if nibbles[hex[i]] > 0xf {
+			return false
+		}
+	}
+	return true
+}
+
+// syntaxError returns an ErrSyntax describing the first character that stops
+// s from being a hex label.
+func syntaxError(s string) error {
+	hex, ok := trimAt(s)
+	if !ok {
+		if s == "" {
+			return ErrSyntax
+		}
+		return prefixError(s[0])
+	}
+	for i := 0; i < len(hex); i++ {
+		if nibbles[hex[i]] > 0xf {
+			// the index in s, including the '@'
+			return hexError(hex[i], i+1)
+		}
+	}
+	return ErrSyntax
+}
+
+// hexValue returns the value of s, which must be no more than 16 hex digits
+// that have already been checked by isHexLabel.
+func hexValue(s string) uint64 {
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		n = n<<4 | uint64(nibbles[s[i]])
+	}
+	return n
+}
+
+// ValidTai64 reports whether s is a valid hex TAI64 string, without parsing it
+// into a time.Time.
+func ValidTai64(s string) bool {
+	_, err := ParseTai64Label(s)
+	return err == nil
+}
+
+// ValidTai64Bytes is like ValidTai64 but checks a hex TAI64 string held in a
+// byte slice.
+func ValidTai64Bytes(b []byte) bool {
+	if len(b) != Tai64HexLen || b[0] != '@' {
+		return false
+	}
+	sec, i := parseHex(b[1:Tai64HexLen])
+	return i < 0 && InFirstHalf(sec)
+}
+
+// ValidTai64n reports whether s is a valid hex TAI64N string, without parsing
+// it into a time.Time.
+func ValidTai64n(s string) bool {
+	_, err := ParseTai64nLabel(s)
+	return err == nil
+}
+
+// ValidTai64nBytes is like ValidTai64n but checks a hex TAI64N string held in
+// a byte slice.
+func ValidTai64nBytes(b []byte) bool {
+	if len(b) != Tai64nHexLen || b[0] != '@' {
+		return false
+	}
+	sec, i := parseHex(b[1:Tai64HexLen])
+	nsec, j := parseHex(b[Tai64HexLen:Tai64nHexLen])
+	return i < 0 && j < 0 && InFirstHalf(sec) && nsec < 1e9
+}
+
+// CompareBytes compares two labels in binary external TAI64, TAI64N or TAI64NA
+// format, returning -1 if a is earlier than b, 0 if they are the same and +1
+// if a is later than b. Labels of different formats can be compared, missing
+// fields are treated as zero. It assumes both labels are valid and at least 8
+// bytes long.
+func CompareBytes(a, b []byte) int {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		var x, y byte
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return +1
+		}
+	}
+	return 0
+}
+
 // EpochTime returns the time.Time at secs seconds and nsec nanoseconds since
 // the beginning of January 1, 1970 TAI.
 func EpochTime(secs, nsecs int64) time.Time {
-	offset := len(leapSeconds) + 10
-	for _, l := range leapSeconds {
-		offset--
-		if secs > l {
-			break
-		}
+	return time.Unix(TAItoUTC(secs), nsecs)
+}
+
+// EpochTimeChecked is like EpochTime but returns ErrRange if nsecs is not
+// between 0 and 999999999, or if secs is so close to the smallest int64 that
+// converting it to UTC would overflow. EpochTime instead normalizes nsecs, as
+// time.Unix does, and wraps around on overflow.
+func EpochTimeChecked(secs, nsecs int64) (time.Time, error) {
+	if nsecs < 0 || nsecs >= 1e9 {
+		return time.Time{}, Error{fmt.Sprintf("tai64: nanoseconds %d out of range", nsecs), ErrRange.message}
+	}
+	// UTC is always behind TAI, so a later result means it wrapped around
+	utc := TAItoUTC(secs)
+	if utc > secs {
+		return time.Time{}, Error{fmt.Sprintf("tai64: seconds %d out of range", secs), ErrRange.message}
+	}
+	return time.Unix(utc, nsecs), nil
+}
+
+// FormatTai64 returns the hex TAI64 string for t, such as "@4000000037c219bf".
+// The hex digits are lowercase, as in daemontools. Any fractional part of the
+// second is discarded, so the result is the label of the second containing t;
+// 15:04:05.9 becomes the label for 15:04:05, not 15:04:06. This rounds towards
+// the past for times before 1970 too.
+func FormatTai64(t time.Time) string {
+	return NewTai64(t).String()
+}
+
+// FormatTai64n returns the hex TAI64N string for t, such as
+// "@4000000037c219bf2ef02e94". The hex digits are lowercase, as in daemontools.
+func FormatTai64n(t time.Time) string {
+	return NewTai64n(t).String()
+}
+
+// FormatTai64nUpper is like FormatTai64n but the hex digits are uppercase,
+// such as "@4000000037C219BF2EF02E94".
+func FormatTai64nUpper(t time.Time) string {
+	return string(NewTai64n(t).appendHex(make([]byte, 0, Tai64nHexLen), upperHexDigits))
+}
+
+// FormatTai64nSafe is like FormatTai64n but returns ErrZeroTime if t is the
+// zero time.Time, which is January 1, year 1 UTC. FormatTai64n formats the zero
+// time as "@3ffffff1886e090a00000000", a valid label that is easy to mistake
+// for real data.
+func FormatTai64nSafe(t time.Time) (string, error) {
+	if t.IsZero() {
+		return "", ErrZeroTime
+	}
+	return FormatTai64n(t), nil
+}
+
+// ToTai64n returns the hex TAI64N string for s, an RFC 3339 time such as
+// "2006-01-02T15:04:05Z" or "2006-01-02T15:04:05.999999999-07:00". It is the
+// inverse of cmd/tai64nlocal. If s is not a valid RFC 3339 time an ErrSyntax is
+// returned.
+func ToTai64n(s string) (string, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return "", Error{fmt.Sprintf("tai64: invalid RFC 3339 time %q", s), ErrSyntax.message}
+	}
+	return FormatTai64n(t), nil
+}
+
+// AppendTai64n appends the hex TAI64N string for t to dst and returns the
+// extended buffer. It is like FormatTai64n but avoids allocating a string.
+func AppendTai64n(dst []byte, t time.Time) []byte {
+	return NewTai64n(t).appendText(dst)
+}
+
+// AppendTai64nBinary appends t in the 12 byte binary external TAI64N format to
+// dst and returns the extended buffer. It is like EncodeTai64n but does not
+// allocate if dst has room, so labels can be written into a larger buffer.
+// SplitTai64nBinary reads them back.
+func AppendTai64nBinary(dst []byte, t time.Time) []byte {
+	return NewTai64n(t).appendBinary(dst)
+}
+
+// AppendTai64nSlice appends each of ts to dst in binary external TAI64N format,
+// packed one after another with no separator, and returns the extended buffer.
+// It is the inverse of DecodeTai64nSlice, and does not allocate if dst has room
+// for 12 bytes per time.
+func AppendTai64nSlice(dst []byte, ts []time.Time) []byte {
+	for _, t := range ts {
+		dst = AppendTai64nBinary(dst, t)
+	}
+	return dst
+}
+
+// EncodeTai64 returns t in the 8 byte binary external TAI64 format. It is the
+// inverse of DecodeTai64. As with FormatTai64 any fractional part of the second
+// is discarded, rounding towards the past.
+func EncodeTai64(t time.Time) []byte {
+	return NewTai64(t).Bytes()
+}
+
+// EncodeTai64n returns t in the 12 byte binary external TAI64N format. It is
+// the inverse of DecodeTai64n. Like every encoder and formatter in this package
+// it ignores any monotonic clock reading in t, so EncodeTai64n(time.Now())
+// gives the same bytes as EncodeTai64n(time.Now().Round(0)) would at that
+// instant.
+func EncodeTai64n(t time.Time) []byte {
+	return NewTai64n(t).Bytes()
+}
+
+// RangeTai64 returns t in the 8 byte binary external TAI64 format for every t
+// from start to end inclusive, step apart. As with EncodeTai64 any fractional
+// part of the second is discarded. The times are step apart in UTC, so labels
+// within a leap second are never included. Start and end are compared by their
+// wall clock readings, ignoring any monotonic clock reading. If end is before
+// start or step is not positive ErrRange is returned.
+func RangeTai64(start, end time.Time, step time.Duration) ([][]byte, error) {
+	start, end = start.Round(0), end.Round(0)
+	if end.Before(start) || step <= 0 {
+		return nil, ErrRange
+	}
+	var labels [][]byte
+	for t := start; !t.After(end); t = t.Add(step) {
+		labels = append(labels, EncodeTai64(t))
 	}
-	return time.Unix(secs-int64(offset), nsecs)
+	return labels, nil
+}
+
+// NowTai64 returns the current time in the 8 byte binary external TAI64
+// format.
+func NowTai64() []byte {
+	return EncodeTai64(nowFunc())
+}
+
+// NowTai64n returns the current time in the 12 byte binary external TAI64N
+// format.
+func NowTai64n() []byte {
+	return EncodeTai64n(nowFunc())
+}
+
+// FormatLocal returns t in loc, formatted using layout. It is shorthand for
+// t.In(loc).Format(layout).
+func FormatLocal(t time.Time, loc *time.Location, layout string) string {
+	return t.In(loc).Format(layout)
+}
+
+// LocalLayout is the layout of the times written by tai64nlocal, with all nine
+// digits of the nanoseconds even when they end in zeros.
+const LocalLayout = "2006-01-02 15:04:05.000000000"
+
+// FormatTai64nLocal returns t in loc formatted as tai64nlocal does, such as
+// "1999-08-23 21:03:43.787492500".
+func FormatTai64nLocal(t time.Time, loc *time.Location) string {
+	return FormatLocal(t, loc, LocalLayout)
+}
+
+// LocalFormatter formats times as tai64nlocal does, with options for other
+// locales. The zero LocalFormatter formats times in time.Local with a '.'
+// before the nanoseconds, the same as FormatTai64nLocal.
+type LocalFormatter struct {
+	// Location is the time zone times are formatted in, or time.Local if nil.
+	Location *time.Location
+	// DecimalSeparator separates the seconds from the nanoseconds, such as
+	// ',' for many European locales. If zero '.' is used.
+	DecimalSeparator byte
+}
+
+// Format returns t formatted using the options in f.
+func (f LocalFormatter) Format(t time.Time) string {
+	loc := f.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	b := t.In(loc).AppendFormat(make([]byte, 0, len(LocalLayout)), LocalLayout)
+	if f.DecimalSeparator != 0 {
+		// the layout ends with the separator and nine digits
+		b[len(b)-10] = f.DecimalSeparator
+	}
+	return string(b)
+}
+
+// FromUnix returns the time sec seconds and nsec nanoseconds since the unix
+// epoch in the 12 byte binary external TAI64N format. As with time.Unix, nsec
+// may be outside the range [0, 999999999].
+func FromUnix(sec, nsec int64) []byte {
+	sec += nsec / 1e9
+	nsec %= 1e9
+	if nsec < 0 {
+		sec--
+		nsec += 1e9
+	}
+	return Tai64n{uint64(UTCtoTAI(sec)) + 1<<62, uint32(nsec)}.Bytes()
+}
+
+// ToUnix decodes a timestamp in binary external TAI64N format into seconds and
+// nanoseconds since the unix epoch. If the data cannot be decoded an Error is
+// returned.
+func ToUnix(b []byte) (sec, nsec int64, err error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return TAItoUTC(int64(l.Label - 1<<62)), int64(l.Nanoseconds), nil
+}
+
+// TaiNanos decodes a timestamp in binary external TAI64N format into the number
+// of nanoseconds since the beginning of 1970 TAI. Unlike a unix time no leap
+// seconds are removed, so the result always increases with the label and never
+// changes when the table of leap seconds is updated. ErrRange is returned for
+// labels more than about 292 years from 1970, which do not fit in an int64. If
+// the data cannot be decoded an Error is returned.
+func TaiNanos(b []byte) (int64, error) {
+	l, err := DecodeTai64nLabel(b)
+	if err != nil {
+		return 0, err
+	}
+	secs := int64(l.Label - (1 << 62))
+	nsecs := int64(l.Nanoseconds)
+	if secs < 0 {
+		// count back from the following second, so that the earliest second
+		// that fits does not overflow before the nanoseconds are added
+		secs, nsecs = secs+1, nsecs-1e9
+	}
+	if secs > math.MaxInt64/1000000000 || secs < math.MinInt64/1000000000 {
+		return 0, rangeError("label", l.Label)
+	}
+	n := secs * 1e9
+	if nsecs > 0 && n > math.MaxInt64-nsecs || nsecs < 0 && n < math.MinInt64-nsecs {
+		return 0, rangeError("label", l.Label)
+	}
+	return n + nsecs, nil
+}
+
+// label returns the TAI64 label for the second containing t. It is the
+// inverse of EpochTime.
+func label(t time.Time) uint64 {
+	return uint64(UTCtoTAI(t.Unix())) + 1<<62
 }
@@ -4,8 +4,18 @@
 package tai64
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 )
 
@@ -27,6 +37,9 @@ var tai64nTests = []struct {
 	{"@4000000037c219bf2ef02e94", []byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e, 0x94}, "1999-08-24T04:03:43.7874925Z"},
 	// `echo @4000000052c65e550cd675fc | TZ=:/usr/share/zoneinfo/right/Etc/UTC tai64nlocal`
 	{"@4000000052c65e550cd675fc", []byte{0x40, 0x00, 0x00, 0x00, 0x52, 0xc6, 0x5e, 0x55, 0x0c, 0xd6, 0x75, 0xfc}, "2014-01-03T06:52:34.2153815Z"},
+	// uppercase hex
+	{"@4000000037C219BF2EF02E94", []byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e, 0x94}, "1999-08-24T04:03:43.7874925Z"},
+	{"@4000000052C65E550CD675FC", []byte{0x40, 0x00, 0x00, 0x00, 0x52, 0xc6, 0x5e, 0x55, 0x0c, 0xd6, 0x75, 0xfc}, "2014-01-03T06:52:34.2153815Z"},
 	// the golang date, converted using http://www.tai64.com/
 	{"@4000000043b9410600000000", []byte{0x40, 0x00, 0x00, 0x00, 0x43, 0xb9, 0x41, 0x06, 0x00, 0x00, 0x00, 0x00}, "2006-01-02T15:04:05Z"},
 
@@ -41,6 +54,18 @@ var tai64nTests = []struct {
 	{"@3FFFFFFFFFFFFFFF00000000", []byte{0x3F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00}, "1969-12-31T23:59:49Z"},
 	// 1992-06-02 08:07:09 TAI
 	{"@400000002a2b2c2d00000000", []byte{0x40, 0x00, 0x00, 0x00, 0x2a, 0x2b, 0x2c, 0x2d, 0x00, 0x00, 0x00, 0x00}, "1992-06-02T08:06:43Z"},
+
+	// before 1972 TAI is always 10 seconds ahead of UTC
+	// the last second of 1971 UTC
+	{"@4000000003c2670900000000", []byte{0x40, 0x00, 0x00, 0x00, 0x03, 0xc2, 0x67, 0x09, 0x00, 0x00, 0x00, 0x00}, "1971-12-31T23:59:59Z"},
+	// the first nanosecond of 1900 UTC, and the second before it
+	{"@3fffffff7c55818a00000001", []byte{0x3f, 0xff, 0xff, 0xff, 0x7c, 0x55, 0x81, 0x8a, 0x00, 0x00, 0x00, 0x01}, "1900-01-01T00:00:00.000000001Z"},
+	{"@3fffffff7c5581893b9ac9ff", []byte{0x3f, 0xff, 0xff, 0xff, 0x7c, 0x55, 0x81, 0x89, 0x3b, 0x9a, 0xc9, 0xff}, "1899-12-31T23:59:59.999999999Z"},
+
+	// either side of the leap second at the end of 2016, when TAI went from 36
+	// to 37 seconds ahead of UTC
+	{"@40000000586846a33b9ac9ff", []byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa3, 0x3b, 0x9a, 0xc9, 0xff}, "2016-12-31T23:59:59.999999999Z"},
+	{"@40000000586846a500000000", []byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa5, 0x00, 0x00, 0x00, 0x00}, "2017-01-01T00:00:00Z"},
 }
 
 var tai64Tests = []struct {
@@ -50,12 +75,154 @@ var tai64Tests = []struct {
 }{
 	{"@4000000037c219bf", []byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf}, "1999-08-24T04:03:43Z"},
 	{"@4000000052c65e55", []byte{0x40, 0x00, 0x00, 0x00, 0x52, 0xc6, 0x5e, 0x55}, "2014-01-03T06:52:34Z"},
+	// uppercase hex
+	{"@4000000037C219BF", []byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf}, "1999-08-24T04:03:43Z"},
+	{"@4000000052C65E55", []byte{0x40, 0x00, 0x00, 0x00, 0x52, 0xc6, 0x5e, 0x55}, "2014-01-03T06:52:34Z"},
 	{"@4000000043b94106", []byte{0x40, 0x00, 0x00, 0x00, 0x43, 0xb9, 0x41, 0x06}, "2006-01-02T15:04:05Z"},
 	{"@4000000000000000", []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "1969-12-31T23:59:50Z"},
 	{"@4000000000000001", []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, "1969-12-31T23:59:51Z"},
 	{"@400000000000000A", []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0A}, "1970-01-01T00:00:00Z"},
 	{"@3FFFFFFFFFFFFFFF", []byte{0x3F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, "1969-12-31T23:59:49Z"},
 	{"@400000002a2b2c2d", []byte{0x40, 0x00, 0x00, 0x00, 0x2a, 0x2b, 0x2c, 0x2d}, "1992-06-02T08:06:43Z"},
+	{"@4000000003c26709", []byte{0x40, 0x00, 0x00, 0x00, 0x03, 0xc2, 0x67, 0x09}, "1971-12-31T23:59:59Z"},
+	{"@40000000586846a3", []byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa3}, "2016-12-31T23:59:59Z"},
+	{"@40000000586846a5", []byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa5}, "2017-01-01T00:00:00Z"},
+	{"@3fffffff7c55818a", []byte{0x3f, 0xff, 0xff, 0xff, 0x7c, 0x55, 0x81, 0x8a}, "1900-01-01T00:00:00Z"},
+}
+
+var tai64naTests = []struct {
+	hex   string
+	bytes []byte
+	time  string
+}{
+	{"@4000000037c219bf2ef02e9400000000", []byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e, 0x94, 0x00, 0x00, 0x00, 0x00}, "1999-08-24T04:03:43.7874925Z"},
+	// the attoseconds are discarded
+	{"@4000000037c219bf2ef02e943b9ac9ff", []byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x2e, 0xf0, 0x2e, 0x94, 0x3b, 0x9a, 0xc9, 0xff}, "1999-08-24T04:03:43.7874925Z"},
+	{"@400000000000000A0000000000000001", []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, "1970-01-01T00:00:00Z"},
+	{"@3FFFFFFFFFFFFFFF0000000000000000", []byte{0x3F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "1969-12-31T23:59:49Z"},
+}
+
+var tai64nBadTests = []struct {
+	in  string
+	err error
+}{
+	// no @
+	{"4000000037c219bf2ef02e94", ErrLength},
+	{"4000000037c219bf2ef02e941", ErrSyntax},
+	// too short
+	{"@4000000037c219bf2ef02e9", ErrLength},
+	// too long
+	{"@4000000037c219bf2ef02e941", ErrLength},
+	// too big a number
+	{"@f000000037c219bf2ef02e94", ErrRange},
+	// too many nanoseconds
+	{"@40000000000000003b9aca00", ErrRange},
+	{"@4000000000000000ffffffff", ErrRange},
+	// not hex
+	{"@G00000000000000000000000", ErrSyntax},
+}
+
+var tai64BadTests = []struct {
+	in  string
+	err error
+}{
+	// no @
+	{"4000000037c219bf", ErrLength},
+	{"4000000037c219bf1", ErrSyntax},
+	// too short
+	{"@4000000037c219b", ErrLength},
+	// too long
+	{"@4000000037c219bf1", ErrLength},
+	// too big a number
+	{"@f000000037c219bf", ErrRange},
+	// not hex
+	{"@G000000000000000", ErrSyntax},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		hex  string
+		time string
+	}{
+		{tai64Tests[0].hex, tai64Tests[0].time},
+		{tai64nTests[0].hex, tai64nTests[0].time},
+		{tai64naTests[0].hex, tai64naTests[0].time},
+	}
+	for _, test := range tests {
+		result, err := Parse(test.hex)
+		if err != nil {
+			t.Errorf("%v: expected nil error, got %v", test.hex, err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%v: got %v, expected %v", test.hex, out, test.time)
+		}
+	}
+
+	bad := []struct {
+		in  string
+		err error
+	}{
+		{"", ErrLength},
+		{"@", ErrLength},
+		{"@4000000037c219bf2", ErrLength},
+		{"@4000000037c219bf2ef02e94 ", ErrLength},
+		{"@4000000037c219bf2ef02e94000000001", ErrLength},
+		{"@4000000037c219bz", ErrSyntax},
+		{"@4000000037c219bf2ef02e9z", ErrSyntax},
+		{"@4000000037c219bf2ef02e940000000z", ErrSyntax},
+		{"@8000000000000000", ErrRange},
+	}
+	for _, test := range bad {
+		result, err := Parse(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("%q: expected zero time, got %v", test.in, result)
+		}
+	}
+}
+
+func TestParseMatchesFormats(t *testing.T) {
+	// Parse must give the same result as the parser for each format
+	tests := []struct {
+		parse func(string) (time.Time, error)
+		in    []string
+	}{
+		{ParseTai64, nil},
+		{ParseTai64n, nil},
+		{ParseTai64na, nil},
+	}
+	for _, test := range tai64Tests {
+		tests[0].in = append(tests[0].in, test.hex)
+	}
+	for _, test := range tai64BadTests {
+		tests[0].in = append(tests[0].in, test.in)
+	}
+	for _, test := range tai64nTests {
+		tests[1].in = append(tests[1].in, test.hex)
+	}
+	for _, test := range tai64nBadTests {
+		tests[1].in = append(tests[1].in, test.in)
+	}
+	for _, test := range tai64naTests {
+		tests[2].in = append(tests[2].in, test.hex)
+	}
+	for _, test := range tests {
+		for _, in := range test.in {
+			if len(in) != 17 && len(in) != 25 && len(in) != 33 {
+				continue
+			}
+			expected, experr := test.parse(in)
+			result, err := Parse(in)
+			if err != experr {
+				t.Errorf("%q: got %v, expected %v", in, err, experr)
+			}
+			if !result.Equal(expected) {
+				t.Errorf("%q: got %v, expected %v", in, result, expected)
+			}
+		}
+	}
 }
 
 func TestParseTai64n(t *testing.T) {
@@ -69,28 +236,281 @@ func TestParseTai64n(t *testing.T) {
 		}
 	}
 
-	bad := []string{
-		// no @
+	for _, test := range tai64nBadTests {
+		result, err := ParseTai64n(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("expected zero time, got %v", result)
+		}
+	}
+}
+
+func TestParseTai64nLenient(t *testing.T) {
+	good := []string{
+		"@4000000037c219bf2ef02e94",
 		"4000000037c219bf2ef02e94",
-		"4000000037c219bf2ef02e941",
-		// too short
-		"@4000000037c219bf2ef02e9",
-		// too long
-		"@4000000037c219bf2ef02e941",
-		// too big a number
-		"@f000000037c219bf2ef02e94",
-		// not hex
-		"@G00000000000000000000000",
+		" @4000000037c219bf2ef02e94 ",
+		"\t4000000037c219bf2ef02e94\n",
+	}
+	for _, test := range good {
+		result, err := ParseTai64nLenient(test)
+		if err != nil {
+			t.Errorf("%q: expected nil error, got %v", test, err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != "1999-08-24T04:03:43.7874925Z" {
+			t.Errorf("%q: got %v", test, out)
+		}
+	}
+
+	bad := []struct {
+		in  string
+		err error
+	}{
+		{"4000000037c219bf2ef02e9", ErrLength},
+		{"@ 4000000037c219bf2ef02e94", ErrLength},
+		{"@@4000000037c219bf2ef02e94", ErrLength},
+		{"G000000037c219bf2ef02e94", ErrSyntax},
+		{" @4000000037c219bf 2ef02e94 ", ErrLength},
+		{"f000000037c219bf2ef02e94", ErrRange},
 	}
 	for _, test := range bad {
-		result, err := ParseTai64n(test)
-		if err != parseError {
-			t.Errorf("expected %v, got %v", parseError, err)
+		result, err := ParseTai64nLenient(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("expected zero time, got %v", result)
+		}
+	}
+}
+
+func TestParseTai64nOpts(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts []Option
+		err  error
+	}{
+		{"@4000000037c219bf2ef02e94", nil, nil},
+		{"4000000037c219bf2ef02e94", nil, ErrLength},
+		{" @4000000037c219bf2ef02e94 ", nil, ErrLength},
+
+		{"@4000000037c219bf2ef02e94", []Option{AllowNoAt()}, nil},
+		{"4000000037c219bf2ef02e94", []Option{AllowNoAt()}, nil},
+		{" 4000000037c219bf2ef02e94", []Option{AllowNoAt()}, ErrLength},
+		{"@@4000000037c219bf2ef02e94", []Option{AllowNoAt()}, ErrLength},
+
+		{"@4000000037c219bf2ef02e94", []Option{AllowWhitespace()}, nil},
+		{" @4000000037c219bf2ef02e94 ", []Option{AllowWhitespace()}, nil},
+		{"\t@4000000037c219bf2ef02e94\n", []Option{AllowWhitespace()}, nil},
+		{" 4000000037c219bf2ef02e94 ", []Option{AllowWhitespace()}, ErrLength},
+		{"@ 4000000037c219bf2ef02e94", []Option{AllowWhitespace()}, ErrLength},
+
+		{" 4000000037c219bf2ef02e94 ", []Option{AllowNoAt(), AllowWhitespace()}, nil},
+		{" 4000000037c219bf2ef02e94 ", []Option{AllowWhitespace(), AllowNoAt()}, nil},
+		{"\t@4000000037c219bf2ef02e94\n", []Option{AllowNoAt(), AllowWhitespace()}, nil},
+		{" 4000000037c219bf 2ef02e94 ", []Option{AllowNoAt(), AllowWhitespace()}, ErrLength},
+
+		// the nanoseconds and label are always checked
+		{"@40000000000000003b9aca00", nil, ErrRange},
+		{"@4000000000000000ffffffff", nil, ErrRange},
+		{"40000000000000003b9aca00", []Option{AllowNoAt(), AllowWhitespace()}, ErrRange},
+		{" 40000000000000003b9aca00 ", []Option{AllowNoAt(), AllowWhitespace()}, ErrRange},
+		{" f000000037c219bf2ef02e94", []Option{AllowNoAt(), AllowWhitespace()}, ErrRange},
+	}
+	for _, test := range tests {
+		result, err := ParseTai64nOpts(test.in, test.opts...)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q with %d options: expected %v, got %v", test.in, len(test.opts), test.err, err)
+		}
+		if test.err != nil {
+			if !result.IsZero() {
+				t.Errorf("%q: expected zero time, got %v", test.in, result)
+			}
+			continue
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != "1999-08-24T04:03:43.7874925Z" {
+			t.Errorf("%q: got %v", test.in, out)
+		}
+	}
+
+	// with no options it is the same as ParseTai64n
+	for _, test := range tai64nBadTests {
+		_, expected := ParseTai64n(test.in)
+		if _, err := ParseTai64nOpts(test.in); err != expected {
+			t.Errorf("%q: got %v, expected %v", test.in, err, expected)
+		}
+	}
+}
+
+func TestParseTai64nHex(t *testing.T) {
+	for _, test := range tai64nTests {
+		for _, in := range []string{test.hex, "0x" + test.hex[1:], "0X" + test.hex[1:], test.hex[1:]} {
+			result, err := ParseTai64nHex(in)
+			if err != nil {
+				t.Errorf("%v: expected nil error, got %v", in, err)
+			}
+			if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+				t.Errorf("%v: got %v, expected %v", in, out, test.time)
+			}
+		}
+	}
+
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{"0x@4000000037c219bf2ef02e9", ErrSyntax},
+		{"@0x4000000037c219bf2ef02e", ErrSyntax},
+		{"0x4000000037c219bf2ef02e9", ErrLength},
+		{"4000000037c219bf2ef02e9", ErrLength},
+		{"0x4000000037c219bf2ef02e941", ErrLength},
+		{"0x", ErrLength},
+		{"", ErrLength},
+		{"0x4000000037c219bf2ef02e9g", ErrSyntax},
+		{"x000000037c219bf2ef02e94", ErrSyntax},
+		{"0xf000000037c219bf2ef02e94", ErrRange},
+	}
+	for _, test := range tests {
+		result, err := ParseTai64nHex(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("%v: expected zero time, got %v", test.in, result)
+		}
+	}
+	_, err := ParseTai64nHex("0x4000000037c219bf2ef02e9")
+	if expected := "tai64: invalid length 25, expected 26"; err == nil || err.Error() != expected {
+		t.Errorf("got %v, expected %v", err, expected)
+	}
+}
+
+func TestParseTai64nBracketed(t *testing.T) {
+	for _, test := range tai64nTests {
+		for _, in := range []string{test.hex, "[" + test.hex + "]", "(" + test.hex + ")", "<" + test.hex + ">"} {
+			result, err := ParseTai64nBracketed(in)
+			if err != nil {
+				t.Errorf("%v: expected nil error, got %v", in, err)
+			}
+			if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+				t.Errorf("%v: got %v, expected %v", in, out, test.time)
+			}
+		}
+	}
+
+	tests := []struct {
+		in  string
+		err error
+	}{
+		// mismatched
+		{"[@4000000037c219bf2ef02e94)", ErrSyntax},
+		{"(@4000000037c219bf2ef02e94>", ErrSyntax},
+		{"<@4000000037c219bf2ef02e94]", ErrSyntax},
+		{"[@4000000037c219bf2ef02e94", ErrSyntax},
+		{"@4000000037c219bf2ef02e94]", ErrLength},
+		{"]@4000000037c219bf2ef02e94[", ErrLength},
+		{"[", ErrSyntax},
+		// nested
+		{"[[@4000000037c219bf2ef02e94]]", ErrSyntax},
+		{"[(@4000000037c219bf2ef02e94)]", ErrSyntax},
+		{"<<>>", ErrSyntax},
+		{"[[@4000000037c219bf2ef02e9]]", ErrSyntax},
+		// not a label inside
+		{"[]", ErrLength},
+		{"[ @4000000037c219bf2ef02e94]", ErrLength},
+		{"[@f000000037c219bf2ef02e94]", ErrRange},
+		{"", ErrLength},
+	}
+	for _, test := range tests {
+		result, err := ParseTai64nBracketed(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("%v: expected zero time, got %v", test.in, result)
+		}
+	}
+	_, err := ParseTai64nBracketed("[@4000000037c219bf2ef02e94)")
+	if expected := "tai64: unmatched bracket '['"; err == nil || err.Error() != expected {
+		t.Errorf("got %v, expected %v", err, expected)
+	}
+}
+
+func TestParseTai64nBytes(t *testing.T) {
+	for _, test := range tai64nTests {
+		result, err := ParseTai64nBytes([]byte(test.hex))
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	for _, test := range tai64nBadTests {
+		result, err := ParseTai64nBytes([]byte(test.in))
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
 		}
 		if !result.IsZero() {
 			t.Errorf("expected zero time, got %v", result)
 		}
 	}
+
+	in := []byte("@4000000037c219bf2ef02e94")
+	allocs := testing.AllocsPerRun(100, func() {
+		ParseTai64nBytes(in)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations, expected 0", allocs)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		bytes []byte
+		time  string
+	}{
+		{tai64Tests[0].bytes, tai64Tests[0].time},
+		{tai64nTests[0].bytes, tai64nTests[0].time},
+		{tai64naTests[0].bytes, tai64naTests[0].time},
+	}
+	for _, test := range tests {
+		result, err := Decode(test.bytes)
+		if err != nil {
+			t.Errorf("%x: expected nil error, got %v", test.bytes, err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%x: got %v, expected %v", test.bytes, out, test.time)
+		}
+	}
+
+	bad := []struct {
+		in  []byte
+		err error
+	}{
+		{nil, ErrLength},
+		{[]byte{0x40}, ErrLength},
+		{append(tai64nTests[0].bytes[:12:12], 0), ErrLength},
+		{append(tai64naTests[0].bytes[:16:16], 0), ErrLength},
+		{[]byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, ErrRange},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, ErrRange},
+	}
+	for _, test := range bad {
+		result, err := Decode(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("%x: expected zero time, got %v", test.in, result)
+		}
+	}
+	_, err := Decode([]byte{0x40})
+	if expected := "tai64: invalid length 1, expected 8, 12 or 16"; err == nil || err.Error() != expected {
+		t.Errorf("got %v, expected %v", err, expected)
+	}
 }
 
 func TestDecodeTai64n(t *testing.T) {
@@ -103,18 +523,24 @@ func TestDecodeTai64n(t *testing.T) {
 			t.Errorf("got %v, expected %v", out, test.time)
 		}
 	}
-	bad := [][]byte{
+	bad := []struct {
+		in  []byte
+		err error
+	}{
 		// too long
-		{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
 		// too short
-		{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
 		// too big a number
-		{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{[]byte{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		// too many nanoseconds
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3b, 0x9a, 0xca, 0x00}, ErrRange},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, ErrRange},
 	}
 	for _, test := range bad {
-		result, err := DecodeTai64n(test)
-		if err != decodeError {
-			t.Errorf("expected %v, got %v", decodeError, err)
+		result, err := DecodeTai64n(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
 		}
 		if !result.IsZero() {
 			t.Errorf("expected zero time, got %v", result)
@@ -133,23 +559,10 @@ func TestParseTai64(t *testing.T) {
 		}
 	}
 
-	bad := []string{
-		// no @
-		"4000000037c219bf",
-		"4000000037c219bf1",
-		// too short
-		"@4000000037c219b",
-		// too long
-		"@4000000037c219bf1",
-		// too big a number
-		"@f000000037c219bf",
-		// not hex
-		"@G000000000000000",
-	}
-	for _, test := range bad {
-		result, err := ParseTai64(test)
-		if err != parseError {
-			t.Errorf("expected %v, got %v", parseError, err)
+	for _, test := range tai64BadTests {
+		result, err := ParseTai64(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
 		}
 		if !result.IsZero() {
 			t.Errorf("expected zero time, got %v", result)
@@ -157,6 +570,60 @@ func TestParseTai64(t *testing.T) {
 	}
 }
 
+func TestParseTai64Padded(t *testing.T) {
+	for _, test := range tai64Tests {
+		for _, in := range []string{test.hex, "@" + strings.TrimLeft(test.hex[1:], "0")} {
+			result, err := ParseTai64Padded(in)
+			if err != nil {
+				t.Errorf("%v: expected nil error, got %v", in, err)
+			}
+			if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+				t.Errorf("%v: got %v, expected %v", in, out, test.time)
+			}
+		}
+	}
+
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{"@0", nil},
+		{"@37c219bf", nil},
+		{"@037c219bf", nil},
+		{"@7fffffffffffffff", nil},
+		{"@", ErrLength},
+		{"", ErrLength},
+		{"@4000000037c219bf0", ErrLength},
+		{"@00000000000000000", ErrLength},
+		{"37c219bf", ErrSyntax},
+		{"@37c219bg", ErrSyntax},
+		{"@37c2 19bf", ErrSyntax},
+		{"@-1", ErrSyntax},
+		{"@8000000000000000", ErrRange},
+	}
+	for _, test := range tests {
+		result, err := ParseTai64Padded(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
+		}
+		if test.err != nil {
+			if !result.IsZero() {
+				t.Errorf("%v: expected zero time, got %v", test.in, result)
+			}
+			continue
+		}
+		// the same as the full length label
+		padded := "@" + strings.Repeat("0", 17-len(test.in)) + test.in[1:]
+		expected, err := ParseTai64(padded)
+		if err != nil {
+			t.Fatalf("%v: expected nil error, got %v", padded, err)
+		}
+		if !result.Equal(expected) {
+			t.Errorf("%v: got %v, expected %v", test.in, result, expected)
+		}
+	}
+}
+
 func TestDecodeTai64(t *testing.T) {
 	for _, test := range tai64Tests {
 		result, err := DecodeTai64(test.bytes)
@@ -167,21 +634,1766 @@ func TestDecodeTai64(t *testing.T) {
 			t.Errorf("got %v, expected %v", out, test.time)
 		}
 	}
-	bad := [][]byte{
+	bad := []struct {
+		in  []byte
+		err error
+	}{
 		// too long
-		{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
 		// too short
-		{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
 		// too big a number
-		{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{[]byte{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+	}
+	for _, test := range bad {
+		result, err := DecodeTai64(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("expected zero time, got %v", result)
+		}
+	}
+}
+
+func TestDecodeTai64Exact(t *testing.T) {
+	for _, test := range tai64Tests {
+		result, err := DecodeTai64Exact(test.bytes)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+		// the same label as TAI64N with no nanoseconds
+		in := append(append([]byte{}, test.bytes...), 0, 0, 0, 0)
+		result, err = DecodeTai64Exact(in)
+		if err != nil {
+			t.Errorf("%x: expected nil error, got %v", in, err)
+		}
+		if out := result.UTC().Format(time.RFC3339); out != test.time {
+			t.Errorf("%x: got %v, expected %v", in, out, test.time)
+		}
+		// which DecodeTai64 does not accept
+		if _, err := DecodeTai64(in); !errors.Is(err, ErrLength) {
+			t.Errorf("%x: expected %v, got %v", in, ErrLength, err)
+		}
+	}
+	bad := []struct {
+		in  []byte
+		err error
+	}{
+		{nil, ErrLength},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
+		{[]byte{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		{[]byte{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		// nanoseconds that would be lost
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x37, 0xc2, 0x19, 0xbf, 0x00, 0x00, 0x00, 0x01}, ErrRange},
+		{tai64nTests[0].bytes, ErrRange},
+		// and nanoseconds that are out of range
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3b, 0x9a, 0xca, 0x00}, ErrRange},
 	}
 	for _, test := range bad {
-		result, err := DecodeTai64(test)
-		if err != decodeError {
-			t.Errorf("expected %v, got %v", decodeError, err)
+		result, err := DecodeTai64Exact(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
 		}
 		if !result.IsZero() {
 			t.Errorf("expected zero time, got %v", result)
 		}
 	}
+	_, err := DecodeTai64Exact(tai64nTests[0].bytes)
+	if expected := "tai64: nanoseconds 0x2ef02e94 not zero"; err == nil || err.Error() != expected {
+		t.Errorf("got %v, expected %v", err, expected)
+	}
+}
+
+func TestSplitTai64n(t *testing.T) {
+	tests := []struct {
+		in   string
+		time string
+		rest string
+		err  error
+	}{
+		{"@4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", "", nil},
+		{"@4000000037c219bf2ef02e94 hello", "1999-08-24T04:03:43.7874925Z", " hello", nil},
+		{"@4000000037c219bf2ef02e94\thello", "1999-08-24T04:03:43.7874925Z", "\thello", nil},
+		// a message starting with hex directly after the label
+		{"@4000000037c219bf2ef02e94deadbeef", "1999-08-24T04:03:43.7874925Z", "deadbeef", nil},
+		{"@4000000037C219BF2EF02E94ABC", "1999-08-24T04:03:43.7874925Z", "ABC", nil},
+		{"", "", "", ErrLength},
+		{"@4000000037c219bf2ef02e9", "", "", ErrLength},
+		{"hello @4000000037c219bf2ef02e94", "", "", ErrSyntax},
+		{"@4000000037c219bf2ef0 e94 hello", "", "", ErrSyntax},
+		{"@f000000037c219bf2ef02e94 hello", "", "", ErrRange},
+	}
+	for _, test := range tests {
+		result, rest, err := SplitTai64n(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.err, err)
+		}
+		if rest != test.rest {
+			t.Errorf("%q: got rest %q, expected %q", test.in, rest, test.rest)
+		}
+		if test.err != nil {
+			continue
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.time)
+		}
+	}
+}
+
+func TestSplitTai64nBinary(t *testing.T) {
+	// labels embedded in a larger message, between a header and a trailer
+	var buf []byte
+	buf = append(buf, "head"...)
+	for _, test := range tai64nTests {
+		in, err := DecodeTai64n(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		buf = AppendTai64nBinary(buf, in)
+	}
+	buf = append(buf, "tail"...)
+	if !bytes.Equal(buf[:4], []byte("head")) {
+		t.Errorf("got %q, expected the header to be unchanged", buf[:4])
+	}
+	rest := buf[4:]
+	for _, test := range tai64nTests {
+		var result time.Time
+		var err error
+		result, rest, err = SplitTai64nBinary(rest)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	if string(rest) != "tail" {
+		t.Errorf("got rest %q, expected %q", rest, "tail")
+	}
+
+	bad := []struct {
+		in  []byte
+		err error
+	}{
+		{nil, ErrLength},
+		{tai64nTests[0].bytes[:11], ErrLength},
+		{[]byte{0xf0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 't', 'a', 'i', 'l'}, ErrRange},
+	}
+	for _, test := range bad {
+		result, rest, err := SplitTai64nBinary(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() || rest != nil {
+			t.Errorf("%x: got %v, %q, expected zero values", test.in, result, rest)
+		}
+	}
+}
+
+func TestAppendTai64nBinary(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := DecodeTai64n(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		expected := append([]byte("prefix"), test.bytes...)
+		if out := AppendTai64nBinary([]byte("prefix"), in); !bytes.Equal(out, expected) {
+			t.Errorf("got %x, expected %x", out, expected)
+		}
+	}
+	// a buffer with room is written in place
+	buf := make([]byte, 2, 2+Tai64nLen)
+	if out := AppendTai64nBinary(buf, time.Unix(1e9, 0)); &out[0] != &buf[0] {
+		t.Errorf("expected the buffer to be reused")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = AppendTai64nBinary(buf[:2], time.Unix(1e9, 0))
+		SplitTai64nBinary(buf[2:])
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations, expected 0", allocs)
+	}
+}
+
+func TestAppendTai64nSlice(t *testing.T) {
+	var ts []time.Time
+	var expected []byte
+	for _, test := range tai64nTests {
+		in, err := DecodeTai64n(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		ts = append(ts, in)
+		expected = append(expected, test.bytes...)
+	}
+	out := AppendTai64nSlice([]byte("prefix"), ts)
+	if !bytes.Equal(out, append([]byte("prefix"), expected...)) {
+		t.Errorf("got %x, expected %x", out, expected)
+	}
+	result, err := DecodeTai64nSlice(out[len("prefix"):])
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(result) != len(ts) {
+		t.Fatalf("got %d times, expected %d", len(result), len(ts))
+	}
+	for i := range ts {
+		if !result[i].Equal(ts[i]) {
+			t.Errorf("%d: got %v, expected %v", i, result[i], ts[i])
+		}
+	}
+	if out := AppendTai64nSlice(nil, nil); out != nil {
+		t.Errorf("got %x, expected nil", out)
+	}
+
+	buf := make([]byte, 0, len(ts)*Tai64nLen)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = AppendTai64nSlice(buf[:0], ts)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations, expected 0", allocs)
+	}
+}
+
+func TestFindTai64n(t *testing.T) {
+	tests := []struct {
+		in    string
+		time  string
+		start int
+	}{
+		{"@4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", 0},
+		{"@4000000037c219bf2ef02e94 message", "1999-08-24T04:03:43.7874925Z", 0},
+		{"host: @4000000037c219bf2ef02e94 message", "1999-08-24T04:03:43.7874925Z", 6},
+		{"host: @4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", 6},
+		{"user@example.com @4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", 17},
+		{"@@4000000037c219bf2ef02e94", "1999-08-24T04:03:43.7874925Z", 1},
+		{"@4000000037c219bf @4000000052c65e550cd675fc", "2014-01-03T06:52:34.2153815Z", 18},
+		{"@f000000037c219bf2ef02e94 @4000000052c65e550cd675fc", "2014-01-03T06:52:34.2153815Z", 26},
+	}
+	for _, test := range tests {
+		result, start, end, err := FindTai64n(test.in)
+		if err != nil {
+			t.Errorf("%q: expected nil error, got %v", test.in, err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.time)
+		}
+		if start != test.start || end != test.start+25 {
+			t.Errorf("%q: got %d:%d, expected %d:%d", test.in, start, end, test.start, test.start+25)
+		}
+	}
+
+	bad := []string{
+		"",
+		"no label",
+		"@4000000037c219bf2ef02e9",
+		"host: @4000000037c219bf2e message",
+		"@4000000037c219bf2ef02e9G",
+	}
+	for _, test := range bad {
+		result, start, end, err := FindTai64n(test)
+		if err != ErrNotFound {
+			t.Errorf("%q: expected %v, got %v", test, ErrNotFound, err)
+		}
+		if !result.IsZero() || start != -1 || end != -1 {
+			t.Errorf("%q: got %v, %d, %d", test, result, start, end)
+		}
+	}
+}
+
+func TestBefore1970(t *testing.T) {
+	tests := []struct {
+		hex  string
+		time string
+	}{
+		{"@3fffffff7c55818a00000000", "1900-01-01T00:00:00Z"},
+		{"@3ffffff95d1af71a00000000", "1066-10-14T09:00:00Z"},
+		{"@3ffffff1886e090a00000000", "0001-01-01T00:00:00Z"},
+	}
+	for _, test := range tests {
+		result, err := ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("%v: expected nil error, got %v", test.hex, err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%v: got %v, expected %v", test.hex, out, test.time)
+		}
+		expected, _ := time.Parse(time.RFC3339Nano, test.time)
+		if out := FormatTai64n(expected); out != test.hex {
+			t.Errorf("%v: got %v, expected %v", test.time, out, test.hex)
+		}
+		// the label is 10 seconds ahead of the unix time, counted backwards
+		// from 2^62
+		secs := int64(binary.BigEndian.Uint64(EncodeTai64(expected))) - 1<<62
+		if secs >= 0 || secs != expected.Unix()+10 {
+			t.Errorf("%v: got %v TAI seconds, expected %v", test.time, secs, expected.Unix()+10)
+		}
+	}
+}
+
+func TestDecodeTai64nStrict(t *testing.T) {
+	for _, test := range tai64nTests {
+		result, err := DecodeTai64nStrict(test.bytes)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+
+	tests := []struct {
+		hex  string
+		time string
+		err  error
+	}{
+		// the first and last seconds of the range
+		{"@3ffffff1886e090a00000000", "0001-01-01T00:00:00Z", nil},
+		{"@4000003afff441a43b9ac9ff", "9999-12-31T23:59:59.999999999Z", nil},
+		// one second either side
+		{"@3ffffff1886e090900000000", "", ErrRange},
+		{"@4000003afff441a500000000", "", ErrRange},
+		// the first and last valid labels
+		{"@000000000000000000000000", "", ErrRange},
+		{"@7fffffffffffffff3b9ac9ff", "", ErrRange},
+		{"@3fffffffffffffff00000000", "1969-12-31T23:59:49Z", nil},
+		{"@800000000000000000000000", "", ErrRange},
+		{"@4000000000000000ffffffff", "", ErrRange},
+	}
+	for _, test := range tests {
+		b, _ := hex.DecodeString(test.hex[1:])
+		result, err := DecodeTai64nStrict(b)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.hex, test.err, err)
+		}
+		if test.err != nil {
+			if !result.IsZero() {
+				t.Errorf("%v: expected zero time, got %v", test.hex, result)
+			}
+			continue
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%v: got %v, expected %v", test.hex, out, test.time)
+		}
+	}
+}
+
+func TestDecodeTai64nLocal(t *testing.T) {
+	loc := time.FixedZone("PDT", -7*60*60)
+	for _, test := range tai64nTests {
+		result, err := DecodeTai64nLocal(test.bytes, loc)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if result.Location() != loc {
+			t.Errorf("got location %v, expected %v", result.Location(), loc)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	if out, err := DecodeTai64nLocal(tai64nTests[0].bytes, loc); out.Format(time.RFC3339Nano) != "1999-08-23T21:03:43.7874925-07:00" {
+		t.Errorf("got %v, %v", out, err)
+	}
+	if _, err := DecodeTai64nLocal([]byte{0x40}, loc); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestDecodeTai64nTAI(t *testing.T) {
+	tests := []struct {
+		bytes []byte
+		tai   string
+	}{
+		// the beginning of 1970 TAI
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "1970-01-01T00:00:00Z"},
+		// 2017-01-01T00:00:00Z, when TAI was 37 seconds ahead of UTC
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa5, 0x00, 0x00, 0x00, 0x00}, "2017-01-01T00:00:37Z"},
+		// the leap second before it
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa4, 0x00, 0x00, 0x00, 0x00}, "2017-01-01T00:00:36Z"},
+		{tai64nTests[0].bytes, "1999-08-24T04:04:15.7874925Z"},
+	}
+	for _, test := range tests {
+		result, err := DecodeTai64nTAI(test.bytes)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.Format(time.RFC3339Nano); out != test.tai {
+			t.Errorf("got %v, expected %v", out, test.tai)
+		}
+	}
+	if _, err := DecodeTai64nTAI([]byte{0x40}); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestDecodeBoth(t *testing.T) {
+	for _, test := range tai64nTests {
+		utc, tai, err := DecodeBoth(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		expected, _ := DecodeTai64n(test.bytes)
+		if !utc.Equal(expected) {
+			t.Errorf("%x: got %v, expected %v", test.bytes, utc, expected)
+		}
+		expected, _ = DecodeTai64nTAI(test.bytes)
+		if tai != expected {
+			t.Errorf("%x: got %v, expected %v", test.bytes, tai, expected)
+		}
+	}
+
+	// 2017-01-01T00:00:00Z, when TAI was 37 seconds ahead of UTC
+	utc, tai, err := DecodeBoth([]byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa5, 0x00, 0x00, 0x00, 0x00})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if out := utc.UTC().Format(time.RFC3339Nano); out != "2017-01-01T00:00:00Z" {
+		t.Errorf("got %v, expected %v", out, "2017-01-01T00:00:00Z")
+	}
+	if out := tai.Format(time.RFC3339Nano); out != "2017-01-01T00:00:37Z" {
+		t.Errorf("got %v, expected %v", out, "2017-01-01T00:00:37Z")
+	}
+	if d := tai.Sub(utc); d != OffsetAt(utc) || d != 37*time.Second {
+		t.Errorf("got %v, expected %v", d, OffsetAt(utc))
+	}
+
+	utc, tai, err = DecodeBoth([]byte{0x40})
+	if !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+	if !utc.IsZero() || !tai.IsZero() {
+		t.Errorf("expected zero times, got %v and %v", utc, tai)
+	}
+}
+
+func TestDecodeTai64nWithOffset(t *testing.T) {
+	tests := []struct {
+		bytes  []byte
+		offset int
+	}{
+		// before 1972 TAI is always 10 seconds ahead of UTC
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, 10},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x03, 0xc2, 0x67, 0x09, 0x00, 0x00, 0x00, 0x00}, 10},
+		{[]byte{0x3f, 0xff, 0xff, 0xff, 0x7c, 0x55, 0x81, 0x8a, 0x00, 0x00, 0x00, 0x01}, 10},
+		// the first leap second, at the end of June 1972, and the second after
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x04, 0xb2, 0x58, 0x0a, 0x00, 0x00, 0x00, 0x00}, 10},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x04, 0xb2, 0x58, 0x0b, 0x00, 0x00, 0x00, 0x00}, 11},
+		{tai64nTests[0].bytes, 32},
+		{tai64nTests[1].bytes, 35},
+		// the last second of 2016, the leap second and the second after
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa3, 0x00, 0x00, 0x00, 0x00}, 36},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa4, 0x00, 0x00, 0x00, 0x00}, 36},
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa5, 0x00, 0x00, 0x00, 0x00}, 37},
+	}
+	for _, test := range tests {
+		result, offset, err := DecodeTai64nWithOffset(test.bytes)
+		if err != nil {
+			t.Fatalf("%x: expected nil error, got %v", test.bytes, err)
+		}
+		if offset != test.offset {
+			t.Errorf("%x: got offset %d, expected %d", test.bytes, offset, test.offset)
+		}
+		expected, _ := DecodeTai64n(test.bytes)
+		if !result.Equal(expected) {
+			t.Errorf("%x: got %v, expected %v", test.bytes, result, expected)
+		}
+		// outside a leap second the offset is the same as OffsetAt
+		if l, _ := DecodeTai64nLabel(test.bytes); !l.IsLeapSecond() && time.Duration(offset)*time.Second != OffsetAt(result) {
+			t.Errorf("%x: got offset %d, expected %v", test.bytes, offset, OffsetAt(result))
+		}
+	}
+	result, offset, err := DecodeTai64nWithOffset([]byte{0x40})
+	if !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+	if !result.IsZero() || offset != 0 {
+		t.Errorf("got %v, %d, expected zero values", result, offset)
+	}
+}
+
+func TestDecodeTai64nInto(t *testing.T) {
+	for _, test := range tai64nTests {
+		var result time.Time
+		if err := DecodeTai64nInto(test.bytes, &result); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	result := time.Unix(1, 0)
+	if err := DecodeTai64nInto([]byte{0x40}, &result); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+	if !result.Equal(time.Unix(1, 0)) {
+		t.Errorf("expected time to be unchanged, got %v", result)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		DecodeTai64nInto(tai64nTests[0].bytes, &result)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations, expected 0", allocs)
+	}
+}
+
+func TestDecodeTai64nSlice(t *testing.T) {
+	var in []byte
+	for _, test := range tai64nTests {
+		in = append(in, test.bytes...)
+	}
+	result, err := DecodeTai64nSlice(in)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(result) != len(tai64nTests) {
+		t.Fatalf("got %v times, expected %v", len(result), len(tai64nTests))
+	}
+	for i, test := range tai64nTests {
+		if out := result[i].UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+
+	if result, err := DecodeTai64nSlice(nil); err != nil || len(result) != 0 {
+		t.Errorf("got %v, %v, expected no times", result, err)
+	}
+
+	tests := []struct {
+		in      []byte
+		err     error
+		message string
+	}{
+		{in[:len(in)-1], ErrLength, fmt.Sprintf("tai64: invalid length %d, expected a multiple of 12", len(in)-1)},
+		{in[:13], ErrLength, "tai64: invalid length 13, expected a multiple of 12"},
+		{append(in[:24:24], 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0), ErrRange, "tai64: label 2: label 0x8000000000000000 out of range"},
+		{append([]byte{0x40, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, in...), ErrRange, "tai64: label 0: nanoseconds 0xffffffff out of range"},
+	}
+	for _, test := range tests {
+		result, err := DecodeTai64nSlice(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
+		} else if err.Error() != test.message {
+			t.Errorf("got %q, expected %q", err.Error(), test.message)
+		}
+		if result != nil {
+			t.Errorf("%x: expected nil, got %v", test.in, result)
+		}
+	}
+}
+
+func TestParseAllTai64n(t *testing.T) {
+	tests := []struct {
+		in    string
+		times []string
+	}{
+		{"", nil},
+		{"no labels @4000000037c219bf", nil},
+		{"@4000000037c219bf2ef02e94", []string{"1999-08-24T04:03:43.7874925Z"}},
+		{
+			"received @4000000037c219bf2ef02e94 processed @4000000052c65e550cd675fc@4000000043b9410600000000 done",
+			[]string{"1999-08-24T04:03:43.7874925Z", "2014-01-03T06:52:34.2153815Z", "2006-01-02T15:04:05Z"},
+		},
+		{
+			"@4000000037c219bf2ef02e94 @G000000037c219bf2ef02e94 @4000000052c65e550cd675fc",
+			[]string{"1999-08-24T04:03:43.7874925Z", "2014-01-03T06:52:34.2153815Z"},
+		},
+	}
+	for _, test := range tests {
+		var out []string
+		for _, result := range ParseAllTai64n(test.in) {
+			out = append(out, result.UTC().Format(time.RFC3339Nano))
+		}
+		if !reflect.DeepEqual(out, test.times) {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.times)
+		}
+	}
+}
+
+func TestParseDecodeTai64n(t *testing.T) {
+	inputs := [][]byte{
+		// too big a number
+		{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		// too many nanoseconds
+		{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3b, 0x9a, 0xca, 0x00},
+	}
+	for _, test := range tai64nTests {
+		inputs = append(inputs, test.bytes)
+	}
+	for _, in := range inputs {
+		parsed, perr := ParseTai64n("@" + hex.EncodeToString(in))
+		decoded, derr := DecodeTai64n(in)
+		if perr != derr {
+			t.Errorf("%x: parse returned %v, decode returned %v", in, perr, derr)
+		}
+		if !parsed.Equal(decoded) {
+			t.Errorf("%x: parsed %v, decoded %v", in, parsed, decoded)
+		}
+	}
+}
+
+func TestParseTai64na(t *testing.T) {
+	for _, test := range tai64naTests {
+		result, err := ParseTai64na(test.hex)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+
+	bad := []struct {
+		in  string
+		err error
+	}{
+		// no @
+		{"4000000037c219bf2ef02e9400000000", ErrLength},
+		{"4000000037c219bf2ef02e94000000001", ErrSyntax},
+		// too short
+		{"@4000000037c219bf2ef02e940000000", ErrLength},
+		// too long
+		{"@4000000037c219bf2ef02e94000000001", ErrLength},
+		// too big a number
+		{"@f000000037c219bf2ef02e9400000000", ErrRange},
+		// too many nanoseconds
+		{"@40000000000000003b9aca0000000000", ErrRange},
+		// too many attoseconds
+		{"@4000000037c219bf2ef02e943b9aca00", ErrRange},
+		// not hex
+		{"@4000000037c219bf2ef02e94G0000000", ErrSyntax},
+	}
+	for _, test := range bad {
+		result, err := ParseTai64na(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("expected zero time, got %v", result)
+		}
+	}
+}
+
+func TestDecodeTai64na(t *testing.T) {
+	for _, test := range tai64naTests {
+		result, err := DecodeTai64na(test.bytes)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+	bad := []struct {
+		in  []byte
+		err error
+	}{
+		// too long
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
+		// too short
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrLength},
+		// too big a number
+		{[]byte{0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		// too many nanoseconds
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3b, 0x9a, 0xca, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		// too many attoseconds
+		{[]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3b, 0x9a, 0xca, 0x00}, ErrRange},
+	}
+	for _, test := range bad {
+		result, err := DecodeTai64na(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.in, test.err, err)
+		}
+		if !result.IsZero() {
+			t.Errorf("expected zero time, got %v", result)
+		}
+	}
+}
+
+func TestDecodeTai64naStrict(t *testing.T) {
+	for _, test := range tai64naTests {
+		result, err := DecodeTai64naStrict(test.bytes)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("got %v, expected %v", out, test.time)
+		}
+	}
+
+	tests := []struct {
+		hex  string
+		time string
+		err  error
+	}{
+		// each counter at its largest value, and one more
+		{"@4000000037c219bf3b9ac9ff00000000", "1999-08-24T04:03:43.999999999Z", nil},
+		{"@4000000037c219bf3b9aca0000000000", "", ErrNanoseconds},
+		{"@4000000037c219bf000000003b9ac9ff", "1999-08-24T04:03:43Z", nil},
+		{"@4000000037c219bf000000003b9aca00", "", ErrAttoseconds},
+		{"@4000000037c219bf3b9ac9ff3b9ac9ff", "1999-08-24T04:03:43.999999999Z", nil},
+		{"@4000000037c219bf3b9aca003b9aca00", "", ErrNanoseconds},
+		// the first and last seconds of the year range
+		{"@3ffffff1886e090a0000000000000000", "0001-01-01T00:00:00Z", nil},
+		{"@4000003afff441a43b9ac9ff3b9ac9ff", "9999-12-31T23:59:59.999999999Z", nil},
+		{"@3ffffff1886e09090000000000000000", "", ErrRange},
+		{"@4000003afff441a50000000000000000", "", ErrRange},
+		{"@80000000000000000000000000000000", "", ErrRange},
+	}
+	for _, test := range tests {
+		b, _ := hex.DecodeString(test.hex[1:])
+		result, err := DecodeTai64naStrict(b)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.hex, test.err, err)
+		}
+		if test.err != nil {
+			if !errors.Is(err, ErrRange) {
+				t.Errorf("%v: expected %v to match %v", test.hex, err, ErrRange)
+			}
+			// only the field that was out of range is reported
+			for _, other := range []error{ErrNanoseconds, ErrAttoseconds} {
+				if other != test.err && errors.Is(err, other) {
+					t.Errorf("%v: expected %v not to match %v", test.hex, err, other)
+				}
+			}
+			continue
+		}
+		if out := result.UTC().Format(time.RFC3339Nano); out != test.time {
+			t.Errorf("%v: got %v, expected %v", test.hex, out, test.time)
+		}
+	}
+}
+
+func TestDegenerateBytes(t *testing.T) {
+	funcs := map[string]func([]byte) (time.Time, error){
+		"DecodeTai64":      DecodeTai64,
+		"DecodeTai64n":     DecodeTai64n,
+		"DecodeTai64na":    DecodeTai64na,
+		"ParseTai64nBytes": ParseTai64nBytes,
+	}
+	inputs := [][]byte{nil, {}, {'@'}, {0x40}}
+	for name, f := range funcs {
+		for _, in := range inputs {
+			result, err := f(in)
+			if !errors.Is(err, ErrLength) {
+				t.Errorf("%s(%#v): expected %v, got %v", name, in, ErrLength, err)
+			}
+			if !result.IsZero() {
+				t.Errorf("%s(%#v): expected zero time, got %v", name, in, result)
+			}
+		}
+	}
+}
+
+func TestTai64Range(t *testing.T) {
+	tests := []struct {
+		hex   string
+		bytes []byte
+		err   error
+	}{
+		{"@7fffffffffffffff", []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil},
+		{"@8000000000000000", []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrRange},
+		{"@8000000000000001", []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, ErrRange},
+	}
+	// the last valid label is 2^62 - 1 seconds after 1970 TAI
+	last := EpochTime(1<<62-1, 0)
+	for _, test := range tests {
+		expected := last
+		if test.err != nil {
+			expected = time.Time{}
+		}
+		result, err := ParseTai64(test.hex)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.hex, test.err, err)
+		}
+		if !result.Equal(expected) {
+			t.Errorf("%v: got %v, expected %v", test.hex, result, expected)
+		}
+		result, err = DecodeTai64(test.bytes)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%x: expected %v, got %v", test.bytes, test.err, err)
+		}
+		if !result.Equal(expected) {
+			t.Errorf("%x: got %v, expected %v", test.bytes, result, expected)
+		}
+		result, err = ParseTai64n(test.hex + "00000000")
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.hex, test.err, err)
+		}
+		if !result.Equal(expected) {
+			t.Errorf("%v: got %v, expected %v", test.hex, result, expected)
+		}
+	}
+}
+
+func TestCompareBytes(t *testing.T) {
+	var labels [][]byte
+	for _, test := range tai64nTests {
+		labels = append(labels, test.bytes)
+	}
+	for _, test := range tai64Tests {
+		labels = append(labels, test.bytes)
+	}
+	decode := func(b []byte) time.Time {
+		if len(b) == 8 {
+			t, _ := DecodeTai64(b)
+			return t
+		}
+		t, _ := DecodeTai64n(b)
+		return t
+	}
+	for _, a := range labels {
+		for _, b := range labels {
+			expected := 0
+			ta, tb := decode(a), decode(b)
+			if ta.Before(tb) {
+				expected = -1
+			} else if ta.After(tb) {
+				expected = +1
+			}
+			if out := CompareBytes(a, b); out != expected {
+				t.Errorf("CompareBytes(%x, %x): got %d, expected %d", a, b, out, expected)
+			}
+		}
+	}
+
+	sorted := append([][]byte{}, labels...)
+	sort.Slice(sorted, func(i, j int) bool { return CompareBytes(sorted[i], sorted[j]) < 0 })
+	for i := 1; i < len(sorted); i++ {
+		if decode(sorted[i-1]).After(decode(sorted[i])) {
+			t.Errorf("expected %x to sort before %x", sorted[i], sorted[i-1])
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, test := range tai64Tests {
+		if !ValidTai64(test.hex) || !ValidTai64Bytes([]byte(test.hex)) {
+			t.Errorf("expected %v to be valid", test.hex)
+		}
+		if ValidTai64n(test.hex) || ValidTai64nBytes([]byte(test.hex)) {
+			t.Errorf("expected %v not to be valid TAI64N", test.hex)
+		}
+	}
+	for _, test := range tai64BadTests {
+		if ValidTai64(test.in) || ValidTai64Bytes([]byte(test.in)) {
+			t.Errorf("expected %v not to be valid", test.in)
+		}
+	}
+	for _, test := range tai64nTests {
+		if !ValidTai64n(test.hex) || !ValidTai64nBytes([]byte(test.hex)) {
+			t.Errorf("expected %v to be valid", test.hex)
+		}
+		if ValidTai64(test.hex) || ValidTai64Bytes([]byte(test.hex)) {
+			t.Errorf("expected %v not to be valid TAI64", test.hex)
+		}
+	}
+	for _, test := range tai64nBadTests {
+		if ValidTai64n(test.in) || ValidTai64nBytes([]byte(test.in)) {
+			t.Errorf("expected %v not to be valid", test.in)
+		}
+	}
+}
+
+func TestTrimAt(t *testing.T) {
+	tests := []struct {
+		in   string
+		out  string
+		trim bool
+	}{
+		{"@4000000037c219bf", "4000000037c219bf", true},
+		{"4000000037c219bf", "4000000037c219bf", false},
+		{"@", "", true},
+		{"@@", "@", true},
+		{"", "", false},
+		{" @4000000037c219bf", " @4000000037c219bf", false},
+	}
+	for _, test := range tests {
+		out, trim := trimAt(test.in)
+		if out != test.out || trim != test.trim {
+			t.Errorf("%q: got %q, %v, expected %q, %v", test.in, out, trim, test.out, test.trim)
+		}
+		if has := HasLabelPrefix(test.in); has != test.trim {
+			t.Errorf("%q: got %v, expected %v", test.in, has, test.trim)
+		}
+		// adding the prefix back gives the original string
+		if trim && "@"+out != test.in {
+			t.Errorf("%q: got %q after adding @", test.in, "@"+out)
+		}
+	}
+}
+
+func TestIsHexLabel(t *testing.T) {
+	tests := []struct {
+		in  string
+		out bool
+	}{
+		{"@0", true},
+		{"@0123456789abcdef", true},
+		{"@0123456789ABCDEF", true},
+		{"@4000000037c219bf2ef02e94", true},
+		{"@4000000037C219bf2EF02e94", true},
+		{"", false},
+		{"@", false},
+		{"4000000037c219bf", false},
+		{"@@4000000037c219bf", false},
+		{"@4000000037c219bf@", false},
+		{"@G000000000000000", false},
+		{"@4000000037c2 19bf", false},
+		{" @4000000037c219bf", false},
+		{"@4000000037c219bf\n", false},
+		{"@4000000037c2\x0019bf", false},
+		{"@4000000037c219bf\x00", false},
+		{"@-1", false},
+		{"@0x1", false},
+	}
+	for _, test := range tests {
+		if out := isHexLabel(test.in); out != test.out {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.out)
+		}
+		if test.out {
+			continue
+		}
+		if err := syntaxError(test.in); !errors.Is(err, ErrSyntax) {
+			t.Errorf("%q: expected %v, got %v", test.in, ErrSyntax, err)
+		}
+	}
+}
+
+func TestEpochs(t *testing.T) {
+	tests := []struct {
+		label string
+		parse func(string) (time.Time, error)
+		time  time.Time
+	}{
+		{TaiEpochTai64, ParseTai64, time.Unix(-10, 0)},
+		{TaiEpochTai64n, ParseTai64n, time.Unix(-10, 0)},
+		{UnixEpochTai64, ParseTai64, time.Unix(0, 0)},
+		{UnixEpochTai64n, ParseTai64n, time.Unix(0, 0)},
+	}
+	for _, test := range tests {
+		result, err := test.parse(test.label)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if !result.Equal(test.time) {
+			t.Errorf("%v: got %v, expected %v", test.label, result, test.time)
+		}
+	}
+	if out := FormatTai64n(time.Unix(0, 0)); out != UnixEpochTai64n {
+		t.Errorf("got %v, expected %v", out, UnixEpochTai64n)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	_, err := ParseTai64n("@G00000000000000000000000")
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("expected %v to match %v", err, ErrParse)
+	}
+	if !errors.Is(err, ErrSyntax) {
+		t.Errorf("expected %v to match %v", err, ErrSyntax)
+	}
+	if errors.Is(err, ErrLength) {
+		t.Errorf("expected %v not to match %v", err, ErrLength)
+	}
+	_, err = ParseTai64n("@40000000000000003b9aca00")
+	if !errors.Is(err, ErrNanoseconds) || !errors.Is(err, ErrRange) || !errors.Is(err, ErrParse) {
+		t.Errorf("expected %v to match %v, %v and %v", err, ErrNanoseconds, ErrRange, ErrParse)
+	}
+	if errors.Is(err, ErrAttoseconds) {
+		t.Errorf("expected %v not to match %v", err, ErrAttoseconds)
+	}
+	if errors.Is(ErrRange, ErrNanoseconds) {
+		t.Errorf("expected %v not to match %v", ErrRange, ErrNanoseconds)
+	}
+	if errors.Is(errors.New("tai64: parse error"), ErrParse) {
+		t.Errorf("expected other errors not to match %v", ErrParse)
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	decode := func(f func([]byte) (time.Time, error), h string) error {
+		b, _ := hex.DecodeString(h)
+		_, err := f(b)
+		return err
+	}
+	parse := func(f func(string) (time.Time, error), s string) error {
+		_, err := f(s)
+		return err
+	}
+	parseBytes := func(s string) error {
+		_, err := ParseTai64nBytes([]byte(s))
+		return err
+	}
+	tests := []struct {
+		err     error
+		kind    error
+		message string
+	}{
+		{parse(ParseTai64n, "@4000000037c219bf2ef02e9"), ErrLength, "tai64: invalid length 24, expected 25"},
+		{parse(ParseTai64n, "4000000037c219bf2ef02e941"), ErrSyntax, "tai64: invalid prefix '4' at index 0, expected '@'"},
+		{parse(ParseTai64n, "@4000g00037c219bf2ef02e94"), ErrSyntax, "tai64: invalid hex 'g' at index 5"},
+		{parse(ParseTai64n, "@4000000037c219bf2ef0 e94"), ErrSyntax, "tai64: invalid hex ' ' at index 21"},
+		{parse(ParseTai64n, "@f000000037c219bf2ef02e94"), ErrRange, "tai64: label 0xf000000037c219bf out of range"},
+		{parse(ParseTai64n, "@40000000000000003b9aca00"), ErrRange, "tai64: nanoseconds 0x3b9aca00 out of range"},
+		{parse(ParseTai64, "@4000000037c219b"), ErrLength, "tai64: invalid length 16, expected 17"},
+		{parse(ParseTai64, "x4000000037c219bf"), ErrSyntax, "tai64: invalid prefix 'x' at index 0, expected '@'"},
+		{parse(ParseTai64, "@4000000037c219bz"), ErrSyntax, "tai64: invalid hex 'z' at index 16"},
+		{parse(ParseTai64, "@8000000000000000"), ErrRange, "tai64: label 0x8000000000000000 out of range"},
+		{parse(ParseTai64na, "@4000000037c219bf2ef02e94"), ErrLength, "tai64: invalid length 25, expected 33"},
+		{parse(ParseTai64na, "#4000000037c219bf2ef02e9400000000"), ErrSyntax, "tai64: invalid prefix '#' at index 0, expected '@'"},
+		{parse(ParseTai64na, "@4000000037c219bf2ef02e940000000G"), ErrSyntax, "tai64: invalid hex 'G' at index 32"},
+		{parse(ParseTai64na, "@f000000037c219bf2ef02e9400000000"), ErrRange, "tai64: label 0xf000000037c219bf out of range"},
+		{parse(ParseTai64na, "@4000000037c219bf3b9aca0000000000"), ErrRange, "tai64: nanoseconds 0x3b9aca00 out of range"},
+		{parse(ParseTai64na, "@4000000037c219bf2ef02e943b9aca00"), ErrRange, "tai64: attoseconds 0x3b9aca00 out of range"},
+		{decode(ParseTai64nBytes, "40"), ErrLength, "tai64: invalid length 1, expected 25"},
+		{parseBytes(" 4000000037c219bf2ef02e94"), ErrSyntax, "tai64: invalid prefix ' ' at index 0, expected '@'"},
+		{parseBytes("@4000000037c219bf2ef02e9x"), ErrSyntax, "tai64: invalid hex 'x' at index 24"},
+		{parseBytes("@8000000037c219bf2ef02e94"), ErrRange, "tai64: label 0x8000000037c219bf out of range"},
+		{parseBytes("@4000000037c219bfffffffff"), ErrRange, "tai64: nanoseconds 0xffffffff out of range"},
+		{decode(DecodeTai64, "4000000037c219"), ErrLength, "tai64: invalid length 7, expected 8"},
+		{decode(DecodeTai64, "8000000000000000"), ErrRange, "tai64: label 0x8000000000000000 out of range"},
+		{decode(DecodeTai64n, "4000000037c219bf2ef02e"), ErrLength, "tai64: invalid length 11, expected 12"},
+		{decode(DecodeTai64n, "f000000037c219bf2ef02e94"), ErrRange, "tai64: label 0xf000000037c219bf out of range"},
+		{decode(DecodeTai64n, "4000000037c219bf3b9aca00"), ErrRange, "tai64: nanoseconds 0x3b9aca00 out of range"},
+		{decode(DecodeTai64nStrict, "4000003afff441a500000000"), ErrRange, "tai64: year 10000 out of range"},
+		{decode(DecodeTai64nStrict, "3ffffff1886e090900000000"), ErrRange, "tai64: year 0 out of range"},
+		{decode(DecodeTai64na, "4000000037c219bf2ef02e94"), ErrLength, "tai64: invalid length 12, expected 16"},
+		{decode(DecodeTai64na, "f000000037c219bf2ef02e9400000000"), ErrRange, "tai64: label 0xf000000037c219bf out of range"},
+		{decode(DecodeTai64na, "4000000037c219bf3b9aca0000000000"), ErrRange, "tai64: nanoseconds 0x3b9aca00 out of range"},
+		{decode(DecodeTai64na, "4000000037c219bf2ef02e943b9aca00"), ErrRange, "tai64: attoseconds 0x3b9aca00 out of range"},
+	}
+	for _, test := range tests {
+		if test.err == nil {
+			t.Errorf("expected %q, got nil", test.message)
+			continue
+		}
+		if msg := test.err.Error(); msg != test.message {
+			t.Errorf("got %q, expected %q", msg, test.message)
+		}
+		if !errors.Is(test.err, test.kind) || !errors.Is(test.err, ErrParse) {
+			t.Errorf("expected %v to match %v and %v", test.err, test.kind, ErrParse)
+		}
+		for _, other := range []error{ErrLength, ErrSyntax, ErrRange, ErrNotFound} {
+			if other != test.kind && errors.Is(test.err, other) {
+				t.Errorf("expected %v not to match %v", test.err, other)
+			}
+		}
+	}
+}
+
+func TestFormatTai64n(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		// output is always lowercase, some fixtures are not
+		if out := FormatTai64n(in); out != strings.ToLower(test.hex) {
+			t.Errorf("got %v, expected %v", out, test.hex)
+		}
+	}
+}
+
+func TestFormatTai64nUpper(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		out := FormatTai64nUpper(in)
+		if expected := "@" + strings.ToUpper(test.hex[1:]); out != expected {
+			t.Errorf("got %v, expected %v", out, expected)
+		}
+		result, err := ParseTai64n(out)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if !result.Equal(in) {
+			t.Errorf("got %v, expected %v", result, in)
+		}
+	}
+}
+
+func TestFormatTai64nSafe(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		out, err := FormatTai64nSafe(in)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if out != FormatTai64n(in) {
+			t.Errorf("got %v, expected %v", out, FormatTai64n(in))
+		}
+	}
+	out, err := FormatTai64nSafe(time.Time{})
+	if out != "" || err != ErrZeroTime {
+		t.Errorf("got %q, %v, expected \"\", %v", out, err, ErrZeroTime)
+	}
+	// the zero time in another location is still zero
+	if _, err := FormatTai64nSafe(time.Time{}.In(time.FixedZone("X", 3600))); err != ErrZeroTime {
+		t.Errorf("got %v, expected %v", err, ErrZeroTime)
+	}
+	// the unsafe version formats it as a label
+	if out := FormatTai64n(time.Time{}); out != "@3ffffff1886e090a00000000" {
+		t.Errorf("got %v, expected %v", out, "@3ffffff1886e090a00000000")
+	}
+}
+
+func TestToTai64n(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		err error
+	}{
+		{"2006-01-02T15:04:05Z", "@4000000043b9410600000000", nil},
+		{"1999-08-24T04:03:43.7874925Z", "@4000000037c219bf2ef02e94", nil},
+		{"2014-01-03T06:52:34.215381500Z", "@4000000052c65e550cd675fc", nil},
+		// non UTC offsets
+		{"2006-01-02T08:04:05-07:00", "@4000000043b9410600000000", nil},
+		{"2014-01-03T12:22:34.2153815+05:30", "@4000000052c65e550cd675fc", nil},
+		// leap seconds are applied
+		{"2016-12-31T23:59:59Z", "@40000000586846a300000000", nil},
+		{"2017-01-01T00:00:00Z", "@40000000586846a500000000", nil},
+		{"", "", ErrSyntax},
+		{"2006-01-02 15:04:05Z", "", ErrSyntax},
+		{"2006-01-02T15:04:05", "", ErrSyntax},
+		{"@4000000043b9410600000000", "", ErrSyntax},
+	}
+	for _, test := range tests {
+		out, err := ToTai64n(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.err, err)
+		}
+		if out != test.out {
+			t.Errorf("%q: got %v, expected %v", test.in, out, test.out)
+		}
+	}
+	_, err := ToTai64n("yesterday")
+	if expected := `tai64: invalid RFC 3339 time "yesterday"`; err == nil || err.Error() != expected {
+		t.Errorf("got %v, expected %v", err, expected)
+	}
+}
+
+func TestFormatTai64(t *testing.T) {
+	for _, test := range tai64Tests {
+		in, err := ParseTai64(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := FormatTai64(in); out != strings.ToLower(test.hex) {
+			t.Errorf("got %v, expected %v", out, test.hex)
+		}
+		// fractional seconds are truncated
+		if out := FormatTai64(in.Add(999999999)); out != strings.ToLower(test.hex) {
+			t.Errorf("got %v, expected %v", out, test.hex)
+		}
+	}
+}
+
+func TestAppendTai64n(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := ParseTai64n(test.hex)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		expected := "prefix " + FormatTai64n(in)
+		if out := AppendTai64n([]byte("prefix "), in); string(out) != expected {
+			t.Errorf("got %s, expected %v", out, expected)
+		}
+	}
+}
+
+func TestFormatTai64Truncation(t *testing.T) {
+	tests := []struct {
+		time  string
+		label string
+	}{
+		{"2006-01-02T15:04:05Z", "@4000000043b94106"},
+		{"2006-01-02T15:04:05.000000001Z", "@4000000043b94106"},
+		{"2006-01-02T15:04:05.5Z", "@4000000043b94106"},
+		{"2006-01-02T15:04:05.9Z", "@4000000043b94106"},
+		{"2006-01-02T15:04:05.999999999Z", "@4000000043b94106"},
+		{"2006-01-02T15:04:06Z", "@4000000043b94107"},
+		// before 1970 TAI, which is 1969-12-31T23:59:50Z
+		{"1969-12-31T23:59:49.9Z", "@3fffffffffffffff"},
+		{"1969-12-31T23:59:50.1Z", "@4000000000000000"},
+	}
+	for _, test := range tests {
+		in, err := time.Parse(time.RFC3339Nano, test.time)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := FormatTai64(in); out != test.label {
+			t.Errorf("%v: got %v, expected %v", test.time, out, test.label)
+		}
+		if out := "@" + hex.EncodeToString(EncodeTai64(in)); out != test.label {
+			t.Errorf("%v: got %v, expected %v", test.time, out, test.label)
+		}
+	}
+}
+
+func TestEncodeTai64n(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, err := DecodeTai64n(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := EncodeTai64n(in); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+	}
+}
+
+// epochTime is a random TAI time for property tests, in seconds and
+// nanoseconds since the beginning of 1970 TAI.
+type epochTime struct {
+	secs, nsecs int64
+}
+
+// Generate implements quick.Generator. The times are between 1900 and 2200,
+// far enough from the limits of time.Time and int64 that nothing overflows.
+// Leap seconds are excluded: EpochTime returns the following second for them,
+// so their labels cannot survive a round trip through time.Time. See
+// TestLeapSecondRoundTrip for how they behave.
+func (epochTime) Generate(r *rand.Rand, size int) reflect.Value {
+	const start, end = -2208988800, 7258118400
+	for {
+		e := epochTime{start + r.Int63n(end-start), r.Int63n(1e9)}
+		if !isLeapSecond(e.secs) {
+			return reflect.ValueOf(e)
+		}
+	}
+}
+
+func TestEncodeDecodeProperty(t *testing.T) {
+	// the instant survives a round trip
+	roundTrip := func(e epochTime) bool {
+		in := EpochTime(e.secs, e.nsecs)
+		out, err := DecodeTai64n(EncodeTai64n(in))
+		return err == nil && out.Equal(in)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+	// and so does the label
+	label := func(e epochTime) bool {
+		expected := Tai64n{uint64(e.secs) + 1<<62, uint32(e.nsecs)}
+		return bytes.Equal(EncodeTai64n(EpochTime(e.secs, e.nsecs)), expected.Bytes())
+	}
+	if err := quick.Check(label, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeMonotonic(t *testing.T) {
+	now := time.Now()
+	wall := now.Round(0)
+	if now == wall {
+		t.Skip("time.Now has no monotonic clock reading on this platform")
+	}
+	if out, expected := EncodeTai64n(now), EncodeTai64n(wall); !bytes.Equal(out, expected) {
+		t.Errorf("got %x, expected %x", out, expected)
+	}
+	if out, expected := EncodeTai64(now), EncodeTai64(wall); !bytes.Equal(out, expected) {
+		t.Errorf("got %x, expected %x", out, expected)
+	}
+	if out, expected := FormatTai64n(now), FormatTai64n(wall); out != expected {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+	if NewTai64n(now) != NewTai64n(wall) || NewTai64(now) != NewTai64(wall) {
+		t.Errorf("expected labels for %v and %v to be equal", now, wall)
+	}
+	// the monotonic reading does not survive a round trip
+	result, err := DecodeTai64n(EncodeTai64n(now))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if result != result.Round(0) || !result.Equal(wall) {
+		t.Errorf("got %v, expected %v", result, wall)
+	}
+	labels, err := RangeTai64(now, now.Add(2*time.Second), time.Second)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	expected, _ := RangeTai64(wall, wall.Add(2*time.Second), time.Second)
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("got %x, expected %x", labels, expected)
+	}
+}
+
+func TestEncodeTai64(t *testing.T) {
+	for _, test := range tai64Tests {
+		in, err := DecodeTai64(test.bytes)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := EncodeTai64(in); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+		// fractional seconds are truncated
+		if out := EncodeTai64(in.Add(999999999)); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+	}
+}
+
+func TestLeapSecondRoundTrip(t *testing.T) {
+	// the labels around 2016-12-31T23:59:60Z, the leap second from IERS
+	// Bulletin C 52
+	tests := []struct {
+		hex  string
+		time string
+		out  string
+	}{
+		{"@40000000586846a200000000", "2016-12-31T23:59:58Z", "@40000000586846a200000000"},
+		{"@40000000586846a300000000", "2016-12-31T23:59:59Z", "@40000000586846a300000000"},
+		{"@40000000586846a33b9ac9ff", "2016-12-31T23:59:59.999999999Z", "@40000000586846a33b9ac9ff"},
+		// time.Time cannot represent 23:59:60, so the leap second becomes the
+		// following second and does not survive the round trip
+		{"@40000000586846a400000000", "2017-01-01T00:00:00Z", "@40000000586846a500000000"},
+		{"@40000000586846a43b9ac9ff", "2017-01-01T00:00:00.999999999Z", "@40000000586846a53b9ac9ff"},
+		{"@40000000586846a500000000", "2017-01-01T00:00:00Z", "@40000000586846a500000000"},
+		{"@40000000586846a500000001", "2017-01-01T00:00:00.000000001Z", "@40000000586846a500000001"},
+		{"@40000000586846a600000000", "2017-01-01T00:00:01Z", "@40000000586846a600000000"},
+	}
+	for _, test := range tests {
+		b, _ := hex.DecodeString(test.hex[1:])
+		decoded, err := DecodeTai64n(b)
+		if err != nil {
+			t.Fatalf("%s: expected nil error, got %v", test.hex, err)
+		}
+		if s := decoded.UTC().Format(time.RFC3339Nano); s != test.time {
+			t.Errorf("%s: got %v, expected %v", test.hex, s, test.time)
+		}
+		if out := FormatTai64n(decoded); out != test.out {
+			t.Errorf("%s: got %v, expected %v", test.hex, out, test.out)
+		}
+		expected, _ := hex.DecodeString(test.out[1:])
+		if out := EncodeTai64n(decoded); !bytes.Equal(out, expected) {
+			t.Errorf("%s: got %x, expected %x", test.hex, out, expected)
+		}
+		// the label types keep the TAI second, so every label round trips
+		label, err := DecodeTai64nLabel(b)
+		if err != nil {
+			t.Fatalf("%s: expected nil error, got %v", test.hex, err)
+		}
+		if out := label.Bytes(); !bytes.Equal(out, b) {
+			t.Errorf("%s: got %x, expected %x", test.hex, out, b)
+		}
+	}
+}
+
+func TestRangeTai64(t *testing.T) {
+	start := time.Date(2016, 12, 31, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		end   time.Time
+		step  time.Duration
+		count int
+		last  string
+	}{
+		{start, time.Second, 1, "@4000000058684668"},
+		// the leap second at the end of 2016 is skipped
+		{start.Add(time.Minute), time.Second, 61, "@40000000586846a5"},
+		{start.Add(time.Minute), 30 * time.Second, 3, "@40000000586846a5"},
+		{start.Add(time.Minute), 25 * time.Second, 3, "@400000005868469a"},
+		{start.Add(time.Minute).Add(-time.Nanosecond), 30 * time.Second, 2, "@4000000058684686"},
+	}
+	for _, test := range tests {
+		labels, err := RangeTai64(start, test.end, test.step)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if len(labels) != test.count {
+			t.Errorf("%v, %v: got %v labels, expected %v", test.end, test.step, len(labels), test.count)
+			continue
+		}
+		if first := fmt.Sprintf("@%x", labels[0]); first != "@4000000058684668" {
+			t.Errorf("got %v, expected %v", first, "@4000000058684668")
+		}
+		if last := fmt.Sprintf("@%x", labels[len(labels)-1]); last != test.last {
+			t.Errorf("%v, %v: got %v, expected %v", test.end, test.step, last, test.last)
+		}
+	}
+
+	for _, step := range []time.Duration{0, -time.Second} {
+		if _, err := RangeTai64(start, start.Add(time.Minute), step); err != ErrRange {
+			t.Errorf("%v: expected %v, got %v", step, ErrRange, err)
+		}
+	}
+	if _, err := RangeTai64(start, start.Add(-time.Nanosecond), time.Second); err != ErrRange {
+		t.Errorf("expected %v, got %v", ErrRange, err)
+	}
+}
+
+func TestNow(t *testing.T) {
+	for _, test := range tai64nTests {
+		in, _ := time.Parse(time.RFC3339Nano, test.time)
+		restore := setNow(in)
+		if out := NowTai64n(); !bytes.Equal(out, test.bytes) {
+			t.Errorf("%v: got %x, expected %x", test.time, out, test.bytes)
+		}
+		if out := NowTai64(); !bytes.Equal(out, test.bytes[:8]) {
+			t.Errorf("%v: got %x, expected %x", test.time, out, test.bytes[:8])
+		}
+		restore()
+	}
+}
+
+func BenchmarkParseTai64nBytes(b *testing.B) {
+	in := []byte("@4000000037c219bf2ef02e94")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTai64nBytes(in)
+	}
+}
+
+// parsedLabel keeps the string built in BenchmarkParseTai64nString alive, as
+// a caller holding the label would. Otherwise the compiler can avoid the
+// allocation in the conversion and the benchmark shows nothing.
+var parsedLabel string
+
+func BenchmarkParseTai64nString(b *testing.B) {
+	in := []byte("@4000000037c219bf2ef02e94")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parsedLabel = string(in)
+		ParseTai64n(parsedLabel)
+	}
+}
+
+func TestFormatLocal(t *testing.T) {
+	in, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	out := FormatLocal(in, time.FixedZone("PDT", -7*60*60), "2006-01-02 15:04:05.000000000 MST")
+	if expected := "1999-08-23 21:03:43.787492500 PDT"; out != expected {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+}
+
+func TestFormatTai64nLocal(t *testing.T) {
+	// from `man 8 tai64nlocal`
+	in, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	pdt := time.FixedZone("PDT", -7*60*60)
+	if out, expected := FormatTai64nLocal(in, pdt), "1999-08-23 21:03:43.787492500"; out != expected {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+	// trailing zeros are kept
+	in, _ = ParseTai64n("@4000000043b9410600000000")
+	if out, expected := FormatTai64nLocal(in, time.UTC), "2006-01-02 15:04:05.000000000"; out != expected {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+}
+
+func TestLocalFormatter(t *testing.T) {
+	in, _ := ParseTai64n("@4000000037c219bf2ef02e94")
+	pdt := time.FixedZone("PDT", -7*60*60)
+	tests := []struct {
+		f        LocalFormatter
+		expected string
+	}{
+		{LocalFormatter{Location: pdt}, "1999-08-23 21:03:43.787492500"},
+		{LocalFormatter{Location: pdt, DecimalSeparator: '.'}, "1999-08-23 21:03:43.787492500"},
+		{LocalFormatter{Location: pdt, DecimalSeparator: ','}, "1999-08-23 21:03:43,787492500"},
+		{LocalFormatter{Location: time.UTC, DecimalSeparator: ','}, "1999-08-24 04:03:43,787492500"},
+		{LocalFormatter{}, FormatTai64nLocal(in, time.Local)},
+	}
+	for _, test := range tests {
+		if out := test.f.Format(in); out != test.expected {
+			t.Errorf("%+v: got %v, expected %v", test.f, out, test.expected)
+		}
+	}
+	// the separator is found even for years with more than four digits
+	far := time.Date(12345, 1, 2, 3, 4, 5, 6, time.UTC)
+	if out, expected := (LocalFormatter{Location: time.UTC, DecimalSeparator: ','}).Format(far), "12345-01-02 03:04:05,000000006"; out != expected {
+		t.Errorf("got %v, expected %v", out, expected)
+	}
+}
+
+func TestEpochTimeChecked(t *testing.T) {
+	tests := []struct {
+		secs, nsecs int64
+		err         error
+	}{
+		{0, 0, nil},
+		{935467455, 787492500, nil},
+		{-1, 999999999, nil},
+		{math.MaxInt64, 0, nil},
+		{math.MinInt64 + 10, 0, nil},
+		{math.MinInt64 + 9, 0, ErrRange},
+		{math.MinInt64, 0, ErrRange},
+		{0, -1, ErrRange},
+		{0, 1e9, ErrRange},
+		{0, math.MaxInt64, ErrRange},
+	}
+	for _, test := range tests {
+		result, err := EpochTimeChecked(test.secs, test.nsecs)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d, %d: expected %v, got %v", test.secs, test.nsecs, test.err, err)
+		}
+		if test.err != nil {
+			if !result.IsZero() {
+				t.Errorf("%d, %d: expected zero time, got %v", test.secs, test.nsecs, result)
+			}
+			continue
+		}
+		if expected := EpochTime(test.secs, test.nsecs); !result.Equal(expected) {
+			t.Errorf("%d, %d: got %v, expected %v", test.secs, test.nsecs, result, expected)
+		}
+	}
+
+	messages := []struct {
+		secs, nsecs int64
+		message     string
+	}{
+		{0, -1, "tai64: nanoseconds -1 out of range"},
+		{0, 1e9, "tai64: nanoseconds 1000000000 out of range"},
+		{math.MinInt64, 0, "tai64: seconds -9223372036854775808 out of range"},
+	}
+	for _, test := range messages {
+		_, err := EpochTimeChecked(test.secs, test.nsecs)
+		if err == nil || err.Error() != test.message {
+			t.Errorf("%d, %d: got %v, expected %v", test.secs, test.nsecs, err, test.message)
+		}
+	}
+}
+
+func TestUnix(t *testing.T) {
+	for _, test := range tai64nTests {
+		tt, err := time.Parse(time.RFC3339Nano, test.time)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if out := FromUnix(tt.Unix(), int64(tt.Nanosecond())); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+		// nanoseconds outside the usual range are normalized
+		if out := FromUnix(tt.Unix()+2, int64(tt.Nanosecond())-2e9); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+		if out := FromUnix(tt.Unix()-1, int64(tt.Nanosecond())+1e9); !bytes.Equal(out, test.bytes) {
+			t.Errorf("got %x, expected %x", out, test.bytes)
+		}
+		sec, nsec, err := ToUnix(test.bytes)
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if sec != tt.Unix() || nsec != int64(tt.Nanosecond()) {
+			t.Errorf("got %d, %d, expected %d, %d", sec, nsec, tt.Unix(), tt.Nanosecond())
+		}
+	}
+	if _, _, err := ToUnix([]byte{0x40}); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func TestTaiNanos(t *testing.T) {
+	for _, test := range tai64nTests {
+		out, err := TaiNanos(test.bytes)
+		if err != nil {
+			t.Fatalf("%x: expected nil error, got %v", test.bytes, err)
+		}
+		// the seconds since 2^62, then the nanoseconds, with no leap seconds
+		secs := int64(binary.BigEndian.Uint64(test.bytes[:8]) - 1<<62)
+		expected := secs*1e9 + int64(binary.BigEndian.Uint32(test.bytes[8:]))
+		if out != expected {
+			t.Errorf("%x: got %d, expected %d", test.bytes, out, expected)
+		}
+		// which is the same as the TAI time in nanoseconds
+		tai, _ := DecodeTai64nTAI(test.bytes)
+		if out != tai.UnixNano() {
+			t.Errorf("%x: got %d, expected %d", test.bytes, out, tai.UnixNano())
+		}
+	}
+
+	tests := []struct {
+		label Tai64n
+		nanos int64
+		err   error
+	}{
+		{Tai64n{1 << 62, 0}, 0, nil},
+		{Tai64n{1<<62 - 1, 999999999}, -1, nil},
+		// a leap second is counted like any other
+		{Tai64n{1<<62 + 1483228836, 0}, 1483228836e9, nil},
+		// the largest and smallest values that fit
+		{Tai64n{1<<62 + 9223372036, 854775807}, math.MaxInt64, nil},
+		{Tai64n{1<<62 + 9223372036, 854775808}, 0, ErrRange},
+		{Tai64n{1<<62 + 9223372037, 0}, 0, ErrRange},
+		{Tai64n{1<<62 - 9223372037, 145224192}, math.MinInt64, nil},
+		{Tai64n{1<<62 - 9223372037, 145224191}, 0, ErrRange},
+		{Tai64n{1<<62 - 9223372038, 999999999}, 0, ErrRange},
+		{Tai64n{1<<63 - 1, 999999999}, 0, ErrRange},
+		{Tai64n{0, 0}, 0, ErrRange},
+	}
+	for _, test := range tests {
+		out, err := TaiNanos(test.label.Bytes())
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.label, test.err, err)
+		}
+		if out != test.nanos {
+			t.Errorf("%v: got %d, expected %d", test.label, out, test.nanos)
+		}
+	}
+	if _, err := TaiNanos([]byte{0x40}); !errors.Is(err, ErrLength) {
+		t.Errorf("expected %v, got %v", ErrLength, err)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	// mostly TAI64N, as in most logs, with some TAI64 and TAI64NA
+	var in []string
+	for i := 0; i < 8; i++ {
+		in = append(in, tai64nTests[i%2].hex)
+	}
+	in = append(in, tai64Tests[0].hex, tai64naTests[0].hex)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Parse(in[i%len(in)])
+	}
+}
+
+func BenchmarkParseTai64n(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTai64n("@4000000037c219bf2ef02e94")
+	}
+}
+
+func BenchmarkDecodeTai64n(b *testing.B) {
+	in := tai64nTests[0].bytes
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DecodeTai64n(in)
+	}
+}
+
+func BenchmarkDecodeTai64nSlice(b *testing.B) {
+	var in []byte
+	for i := 0; i < 100; i++ {
+		in = append(in, tai64nTests[0].bytes...)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DecodeTai64nSlice(in)
+	}
+}
+
+func BenchmarkDecodeTai64nInto(b *testing.B) {
+	in := tai64nTests[0].bytes
+	var t time.Time
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DecodeTai64nInto(in, &t)
+	}
+}
+
+func FuzzParseTai64n(f *testing.F) {
+	for _, test := range tai64nTests {
+		f.Add(test.hex)
+	}
+	for _, test := range tai64nBadTests {
+		f.Add(test.in)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		result, err := ParseTai64n(s)
+		if err != nil {
+			if !result.IsZero() {
+				t.Errorf("%q: expected zero time, got %v", s, result)
+			}
+			return
+		}
+		if out, err := ParseTai64n(FormatTai64n(result)); err != nil || !out.Equal(result) {
+			t.Errorf("%q: got %v, %v, expected %v", s, out, err, result)
+		}
+		l, err := ParseTai64nLabel(s)
+		if err != nil {
+			t.Fatalf("%q: expected nil error, got %v", s, err)
+		}
+		if out := l.String(); out != strings.ToLower(s) {
+			t.Errorf("got %v, expected %v", out, s)
+		}
+	})
+}
+
+func FuzzDecodeTai64n(f *testing.F) {
+	for _, test := range tai64nTests {
+		f.Add(test.bytes)
+	}
+	f.Add([]byte{})
+	f.Add([]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		result, err := DecodeTai64n(b)
+		if err != nil {
+			if !result.IsZero() {
+				t.Errorf("%x: expected zero time, got %v", b, result)
+			}
+			return
+		}
+		if out, err := DecodeTai64n(EncodeTai64n(result)); err != nil || !out.Equal(result) {
+			t.Errorf("%x: got %v, %v, expected %v", b, out, err, result)
+		}
+		l, err := DecodeTai64nLabel(b)
+		if err != nil {
+			t.Fatalf("%x: expected nil error, got %v", b, err)
+		}
+		if out := l.Bytes(); !bytes.Equal(out, b) {
+			t.Errorf("got %x, expected %x", out, b)
+		}
+	})
+}
+
+func BenchmarkAppendTai64n(b *testing.B) {
+	t := time.Unix(1e9, 123456789)
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendTai64n(buf[:0], t)
+	}
+}
+
+func BenchmarkAppendTai64nBinary(b *testing.B) {
+	t := time.Unix(1e9, 123456789)
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendTai64nBinary(buf[:0], t)
+	}
+}
+
+func BenchmarkAppendTai64nSlice(b *testing.B) {
+	ts := make([]time.Time, 100)
+	for i := range ts {
+		ts[i] = time.Unix(1e9+int64(i), 123456789)
+	}
+	buf := make([]byte, 0, len(ts)*Tai64nLen)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendTai64nSlice(buf[:0], ts)
+	}
+}
+
+func BenchmarkSplitTai64nBinary(b *testing.B) {
+	in := append(append([]byte{}, tai64nTests[0].bytes...), "tail"...)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SplitTai64nBinary(in)
+	}
+}
+
+func BenchmarkAppendFormatTai64n(b *testing.B) {
+	t := time.Unix(1e9, 123456789)
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = append(buf[:0], FormatTai64n(t)...)
+	}
 }